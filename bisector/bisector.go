@@ -0,0 +1,71 @@
+package bisector
+
+import (
+	"github.com/michael1026/paramfinderSlimmed/types/scan"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// Probe sends a subset of a chunk and returns the detector's findings for it
+type Probe func(params map[string]string) []scan.Finding
+
+/***********************************************************************
+*
+* Recursively splits a chunk that produced a chunk-level finding and
+* probes each half. Named findings from a half are kept as they are,
+* halves that still produce a chunk-level finding are split again until
+* a single parameter is left. Halves that stop diverging are dropped, so
+* a change that only happens when several parameters are combined is
+* not reported.
+*
+************************************************************************/
+
+func Bisect(finding scan.Finding, params map[string]string, probe Probe) []scan.Finding {
+	var found []scan.Finding
+
+	if len(params) == 1 {
+		finding.Name = maps.Keys(params)[0]
+		return []scan.Finding{finding}
+	}
+
+	for _, half := range Halve(params) {
+		if len(half) == 0 {
+			continue
+		}
+
+		var chunkFinding *scan.Finding
+
+		for _, halfFinding := range probe(half) {
+			if halfFinding.Name != "" {
+				found = append(found, halfFinding)
+			} else if chunkFinding == nil {
+				halfFinding := halfFinding
+				chunkFinding = &halfFinding
+			}
+		}
+
+		if chunkFinding != nil {
+			found = append(found, Bisect(*chunkFinding, half, probe)...)
+		}
+	}
+
+	return found
+}
+
+func Halve(params map[string]string) []map[string]string {
+	names := maps.Keys(params)
+	slices.Sort(names)
+
+	left := make(map[string]string)
+	right := make(map[string]string)
+
+	for i, name := range names {
+		if i < len(names)/2 {
+			left[name] = params[name]
+		} else {
+			right[name] = params[name]
+		}
+	}
+
+	return []map[string]string{left, right}
+}
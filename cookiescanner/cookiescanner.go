@@ -0,0 +1,149 @@
+package cookiescanner
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/michael1026/paramfinderSlimmed/detector"
+	"github.com/michael1026/paramfinderSlimmed/types/scan"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+type Detector struct{}
+
+func (Detector) Name() string {
+	return "set-cookie"
+}
+
+func (Detector) Detect(urlInfo *scan.URLInfo, resp *detector.Response) []scan.Finding {
+	diffs := CheckCookiesForDiff(resp.Header, urlInfo)
+
+	if len(diffs) == 0 {
+		return nil
+	}
+
+	return []scan.Finding{{Detector: "set-cookie", Reason: strings.Join(diffs, ", "), Headers: []string{"Set-Cookie"}}}
+}
+
+/***********************************************************************
+*
+* Returns the cookies set by a response, mapped to their attributes.
+* Values are left out since session cookies change on every request,
+* and so is the Expires date, only whether the cookie has one.
+*
+************************************************************************/
+
+func Cookies(header http.Header) map[string]string {
+	cookies := make(map[string]string)
+
+	for _, cookie := range (&http.Response{Header: header}).Cookies() {
+		attributes := []string{
+			"path=" + cookie.Path,
+			"domain=" + cookie.Domain,
+			fmt.Sprintf("max-age=%d", cookie.MaxAge),
+			fmt.Sprintf("expires=%t", !cookie.Expires.IsZero() || cookie.RawExpires != ""),
+			fmt.Sprintf("secure=%t", cookie.Secure),
+			fmt.Sprintf("httponly=%t", cookie.HttpOnly),
+			fmt.Sprintf("samesite=%s", sameSite(cookie.SameSite)),
+		}
+
+		cookies[cookie.Name] = strings.Join(attributes, "; ")
+	}
+
+	return cookies
+}
+
+// cookies that aren't set the same way in every baseline sample are dynamic
+func DynamicCookies(headers []http.Header) []string {
+	var dynamic []string
+
+	if len(headers) == 0 {
+		return dynamic
+	}
+
+	first := Cookies(headers[0])
+
+	for _, header := range headers[1:] {
+		cookies := Cookies(header)
+
+		for name, attributes := range cookies {
+			if first[name] != attributes && !slices.Contains(dynamic, name) {
+				dynamic = append(dynamic, name)
+			}
+		}
+
+		for name := range first {
+			if _, ok := cookies[name]; !ok && !slices.Contains(dynamic, name) {
+				dynamic = append(dynamic, name)
+			}
+		}
+	}
+
+	slices.Sort(dynamic)
+
+	return dynamic
+}
+
+func CheckCookiesForDiff(header http.Header, urlInfo *scan.URLInfo) []string {
+	var diffs []string
+
+	baseline := urlInfo.Baseline
+	current := Cookies(header)
+
+	names := maps.Keys(current)
+	slices.Sort(names)
+
+	for _, name := range names {
+		if slices.Contains(baseline.DynamicCookies, name) {
+			continue
+		}
+
+		if attributes, ok := baseline.Cookies[name]; !ok {
+			diffs = append(diffs, fmt.Sprintf("new cookie %s", name))
+		} else if attributes != current[name] {
+			diffs = append(diffs, fmt.Sprintf("cookie %s %s", name, attributeDiff(attributes, current[name])))
+		}
+	}
+
+	names = maps.Keys(baseline.Cookies)
+	slices.Sort(names)
+
+	for _, name := range names {
+		if _, ok := current[name]; !ok && !slices.Contains(baseline.DynamicCookies, name) {
+			diffs = append(diffs, fmt.Sprintf("missing cookie %s", name))
+		}
+	}
+
+	return diffs
+}
+
+// only the attributes that changed, both lists come from Cookies so they line up
+func attributeDiff(baseline string, current string) string {
+	var changed []string
+
+	baselineAttributes := strings.Split(baseline, "; ")
+	currentAttributes := strings.Split(current, "; ")
+
+	for i := range baselineAttributes {
+		if i < len(currentAttributes) && baselineAttributes[i] != currentAttributes[i] {
+			changed = append(changed, baselineAttributes[i]+" -> "+currentAttributes[i])
+		}
+	}
+
+	return strings.Join(changed, ", ")
+}
+
+func sameSite(mode http.SameSite) string {
+	switch mode {
+	case http.SameSiteLaxMode:
+		return "lax"
+	case http.SameSiteStrictMode:
+		return "strict"
+	case http.SameSiteNoneMode:
+		return "none"
+	}
+
+	return ""
+}
@@ -0,0 +1,149 @@
+package detector
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/michael1026/paramfinderSlimmed/types/scan"
+	"github.com/michael1026/paramfinderSlimmed/util"
+)
+
+type Response struct {
+	URL        string
+	Method     string
+	StatusCode int
+	Header     http.Header
+	Body       string
+	// the body before dynamic regions are masked
+	RawBody    string
+	RawQuery   string
+	Parameters map[string]string
+	// set by the caller, from sending the request to reading the body
+	Duration time.Duration
+	// name of the injection point the parameters were sent at
+	InjectionPoint string
+}
+
+/***********************************************************************
+*
+* A Detector compares a chunk response against the URL's baseline and
+* returns what it found. A finding without a name applies to the whole
+* chunk and gets bisected to find the responsible parameters.
+*
+************************************************************************/
+
+type Detector interface {
+	Name() string
+	Detect(urlInfo *scan.URLInfo, resp *Response) []scan.Finding
+}
+
+/***********************************************************************
+*
+* Implemented by detectors that only look for the values that were sent
+* and never compare against the baseline. Only these still run on URLs
+* whose baseline samples weren't stable.
+*
+************************************************************************/
+
+type BaselineIndependent interface {
+	IgnoresBaseline() bool
+}
+
+func IgnoresBaseline(d Detector) bool {
+	independent, ok := d.(BaselineIndependent)
+
+	return ok && independent.IgnoresBaseline()
+}
+
+// Implemented by detectors that can tell how a value was encoded when it
+// holds characters the encodings transform. Only used to fill in the
+// encodings of a confirmed finding, never to confirm it.
+type EncodingProber interface {
+	EncodingProbeValue() string
+}
+
+func NewResponse(rawUrl string, params map[string]string, resp *http.Response) *Response {
+	response := &Response{
+		URL:        rawUrl,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       util.ResponseToBodyString(resp),
+		Parameters: params,
+	}
+
+	response.RawBody = response.Body
+
+	if resp.Request != nil {
+		response.Method = resp.Request.Method
+		response.RawQuery = resp.Request.URL.RawQuery
+	}
+
+	return response
+}
+
+type Registry struct {
+	detectors []Detector
+	enabled   map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		enabled: make(map[string]bool),
+	}
+}
+
+func (r *Registry) Register(d Detector, enabled bool) {
+	r.detectors = append(r.detectors, d)
+	r.enabled[d.Name()] = enabled
+}
+
+func (r *Registry) Enable(names []string) error {
+	return r.set(names, true)
+}
+
+func (r *Registry) Disable(names []string) error {
+	return r.set(names, false)
+}
+
+func (r *Registry) set(names []string, enabled bool) error {
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+
+		if _, ok := r.enabled[name]; !ok {
+			return fmt.Errorf("unknown detector %q", name)
+		}
+
+		r.enabled[name] = enabled
+	}
+
+	return nil
+}
+
+func (r *Registry) IsEnabled(name string) bool {
+	return r.enabled[name]
+}
+
+func (r *Registry) Enabled() []Detector {
+	var detectors []Detector
+
+	for _, d := range r.detectors {
+		if r.enabled[d.Name()] {
+			detectors = append(detectors, d)
+		}
+	}
+
+	return detectors
+}
+
+func (r *Registry) Names() []string {
+	var names []string
+
+	for _, d := range r.detectors {
+		names = append(names, d.Name())
+	}
+
+	return names
+}
@@ -0,0 +1,93 @@
+package diffscanner
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/michael1026/paramfinderSlimmed/detector"
+	"github.com/michael1026/paramfinderSlimmed/types/scan"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+type Detector struct{}
+
+func (Detector) Name() string {
+	return "diff"
+}
+
+func (Detector) Detect(urlInfo *scan.URLInfo, resp *detector.Response) []scan.Finding {
+	diffs := CheckResponseForDiff(resp.StatusCode, resp.Header, resp.Body, resp.Parameters, urlInfo)
+
+	if len(diffs) == 0 {
+		return nil
+	}
+
+	return []scan.Finding{{Detector: "diff", Reason: strings.Join(diffs, ", ")}}
+}
+
+func NewBaseline(statusCode int, header http.Header, body string) scan.Baseline {
+	headerNames := maps.Keys(header)
+	slices.Sort(headerNames)
+
+	return scan.Baseline{
+		StatusCode:  statusCode,
+		BodyLength:  len(body),
+		WordCount:   len(strings.Fields(body)),
+		LineCount:   strings.Count(body, "\n") + 1,
+		HeaderNames: headerNames,
+		Location:    header.Get("Location"),
+	}
+}
+
+/***********************************************************************
+*
+* Compares a chunk response against the URL's baseline. Values that were
+* sent in the chunk are removed first so a plain reflection doesn't count
+* as a change in length. Returns a description of each difference.
+*
+************************************************************************/
+
+func CheckResponseForDiff(statusCode int, header http.Header, body string, params map[string]string, urlInfo *scan.URLInfo) []string {
+	var diffs []string
+
+	body = strings.ReplaceAll(body, urlInfo.CanaryValue, "")
+
+	for _, value := range params {
+		body = strings.ReplaceAll(body, value, "")
+	}
+
+	current := NewBaseline(statusCode, header, body)
+	baseline := urlInfo.Baseline
+
+	if current.StatusCode != baseline.StatusCode {
+		diffs = append(diffs, fmt.Sprintf("status code %d -> %d", baseline.StatusCode, current.StatusCode))
+	}
+
+	if current.BodyLength != baseline.BodyLength {
+		diffs = append(diffs, fmt.Sprintf("body length %d -> %d", baseline.BodyLength, current.BodyLength))
+	}
+
+	if current.WordCount != baseline.WordCount {
+		diffs = append(diffs, fmt.Sprintf("word count %d -> %d", baseline.WordCount, current.WordCount))
+	}
+
+	if current.LineCount != baseline.LineCount {
+		diffs = append(diffs, fmt.Sprintf("line count %d -> %d", baseline.LineCount, current.LineCount))
+	}
+
+	for _, name := range current.HeaderNames {
+		if !slices.Contains(baseline.HeaderNames, name) && !slices.Contains(baseline.DynamicHeaders, name) {
+			diffs = append(diffs, fmt.Sprintf("new header %s", name))
+		}
+	}
+
+	for _, name := range baseline.HeaderNames {
+		if !slices.Contains(current.HeaderNames, name) && !slices.Contains(baseline.DynamicHeaders, name) {
+			diffs = append(diffs, fmt.Sprintf("missing header %s", name))
+		}
+	}
+
+	return diffs
+}
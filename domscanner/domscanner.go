@@ -0,0 +1,135 @@
+package domscanner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/michael1026/paramfinderSlimmed/detector"
+	"github.com/michael1026/paramfinderSlimmed/types/scan"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+type Detector struct{}
+
+func (Detector) Name() string {
+	return "dom"
+}
+
+func (Detector) Detect(urlInfo *scan.URLInfo, resp *detector.Response) []scan.Finding {
+	if urlInfo.Baseline.DOM.TagCounts == nil {
+		return nil
+	}
+
+	diffs := CheckDOMForDiff(resp.Body, resp.Parameters, urlInfo)
+
+	if len(diffs) == 0 {
+		return nil
+	}
+
+	return []scan.Finding{{Detector: "dom", Reason: strings.Join(diffs, ", ")}}
+}
+
+/***********************************************************************
+*
+* Records the shape of a page rather than its text: how many of each
+* tag it has, the title, the headings and each form with its fields.
+* Dynamic text changes none of these, so they hold up better than the
+* body length.
+*
+************************************************************************/
+
+func NewDOM(body string) (scan.DOM, bool) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+
+	if err != nil || doc == nil {
+		return scan.DOM{}, false
+	}
+
+	dom := scan.DOM{
+		TagCounts: make(map[string]int),
+		Title:     strings.TrimSpace(doc.Find("title").First().Text()),
+	}
+
+	doc.Find("*").Each(func(index int, item *goquery.Selection) {
+		dom.TagCounts[goquery.NodeName(item)]++
+	})
+
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(index int, item *goquery.Selection) {
+		dom.Headings = append(dom.Headings, goquery.NodeName(item)+": "+strings.Join(strings.Fields(item.Text()), " "))
+	})
+
+	doc.Find("form").Each(func(index int, item *goquery.Selection) {
+		dom.Forms = append(dom.Forms, formStructure(item))
+	})
+
+	return dom, true
+}
+
+// method, action and the sorted field names of a form
+func formStructure(form *goquery.Selection) string {
+	var fields []string
+
+	form.Find("input, select, textarea, button").Each(func(index int, item *goquery.Selection) {
+		if name, ok := item.Attr("name"); ok {
+			fields = append(fields, name)
+		}
+	})
+
+	sort.Strings(fields)
+
+	method := strings.ToUpper(form.AttrOr("method", "GET"))
+
+	return fmt.Sprintf("%s %s (%s)", method, form.AttrOr("action", ""), strings.Join(fields, ", "))
+}
+
+/***********************************************************************
+*
+* Compares a chunk response's DOM against the baseline. The canary and
+* the sent values are removed first, like diffscanner does, so a value
+* reflected in a heading isn't reported here.
+*
+************************************************************************/
+
+func CheckDOMForDiff(body string, params map[string]string, urlInfo *scan.URLInfo) []string {
+	var diffs []string
+
+	body = strings.ReplaceAll(body, urlInfo.CanaryValue, "")
+
+	for _, value := range params {
+		body = strings.ReplaceAll(body, value, "")
+	}
+
+	current, ok := NewDOM(body)
+
+	if !ok {
+		return nil
+	}
+
+	baseline := urlInfo.Baseline.DOM
+
+	tags := append(maps.Keys(baseline.TagCounts), maps.Keys(current.TagCounts)...)
+	slices.Sort(tags)
+
+	for _, tag := range slices.Compact(tags) {
+		if baseline.TagCounts[tag] != current.TagCounts[tag] {
+			diffs = append(diffs, fmt.Sprintf("%s tags %d -> %d", tag, baseline.TagCounts[tag], current.TagCounts[tag]))
+		}
+	}
+
+	if current.Title != baseline.Title {
+		diffs = append(diffs, fmt.Sprintf("title %q -> %q", baseline.Title, current.Title))
+	}
+
+	if !slices.Equal(current.Headings, baseline.Headings) {
+		diffs = append(diffs, "headings changed")
+	}
+
+	if !slices.Equal(current.Forms, baseline.Forms) {
+		diffs = append(diffs, "forms changed")
+	}
+
+	return diffs
+}
@@ -0,0 +1,282 @@
+package injection
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/michael1026/paramfinderSlimmed/requesttemplate"
+)
+
+type Param struct {
+	Name  string
+	Value string
+}
+
+/***********************************************************************
+*
+* A Point is a place in a request that candidate names are injected
+* into. Every stage of the pipeline (stability, size probing, chunking,
+* bisection and verification) builds its requests through the same
+* Point, so a new location only has to implement this.
+*
+************************************************************************/
+
+type Point interface {
+	Name() string
+	// Accepts reports whether a name can be written at this point
+	Accepts(name string) bool
+	// Inject writes params into req. The first param is always the canary.
+	Inject(req *http.Request, params []Param) error
+}
+
+type Query struct{}
+
+func (Query) Name() string { return "query" }
+
+func (Query) Accepts(name string) bool { return name != "" }
+
+func (Query) Inject(req *http.Request, params []Param) error {
+	pairs := make([]string, 0, len(params)+1)
+
+	for _, param := range params {
+		pairs = append(pairs, url.QueryEscape(param.Name)+"="+url.QueryEscape(param.Value))
+	}
+
+	if existing := req.URL.Query().Encode(); existing != "" {
+		pairs = append(pairs, existing)
+	}
+
+	req.URL.RawQuery = strings.Join(pairs, "&")
+
+	return nil
+}
+
+type Form struct{}
+
+func (Form) Name() string { return "form" }
+
+func (Form) Accepts(name string) bool { return name != "" }
+
+func (Form) Inject(req *http.Request, params []Param) error {
+	pairs := make([]string, 0, len(params))
+
+	for _, param := range params {
+		pairs = append(pairs, url.QueryEscape(param.Name)+"="+url.QueryEscape(param.Value))
+	}
+
+	setBody(req, "application/x-www-form-urlencoded", []byte(strings.Join(pairs, "&")))
+
+	return nil
+}
+
+type JSON struct{}
+
+func (JSON) Name() string { return "json" }
+
+func (JSON) Accepts(name string) bool { return name != "" }
+
+func (JSON) Inject(req *http.Request, params []Param) error {
+	object := make(map[string]string)
+
+	for _, param := range params {
+		object[param.Name] = param.Value
+	}
+
+	body, err := json.Marshal(object)
+
+	if err != nil {
+		return err
+	}
+
+	setBody(req, "application/json", body)
+
+	return nil
+}
+
+// XML wraps the params in a plain root element, or in SoapTemplate at its marker
+type XML struct {
+	SoapTemplate string
+}
+
+func (XML) Name() string { return "xml" }
+
+var xmlNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9._-]*$`)
+
+// only plain element names without a namespace prefix can be sent as XML
+func (XML) Accepts(name string) bool {
+	return xmlNameRegex.MatchString(name) && !strings.HasPrefix(strings.ToLower(name), "xml")
+}
+
+func (x XML) Inject(req *http.Request, params []Param) error {
+	elements := &strings.Builder{}
+
+	for _, param := range params {
+		elements.WriteString(xmlElement(param.Name, param.Value))
+	}
+
+	if x.SoapTemplate != "" {
+		setBody(req, "text/xml; charset=utf-8", []byte(strings.Replace(x.SoapTemplate, requesttemplate.Marker, elements.String(), 1)))
+		return nil
+	}
+
+	setBody(req, "application/xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><root>`+elements.String()+`</root>`))
+
+	return nil
+}
+
+type Multipart struct{}
+
+func (Multipart) Name() string { return "multipart" }
+
+func (Multipart) Accepts(name string) bool { return name != "" }
+
+// the writer picks a random boundary for every request
+func (Multipart) Inject(req *http.Request, params []Param) error {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	for _, param := range params {
+		if err := writer.WriteField(param.Name, param.Value); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	setBody(req, writer.FormDataContentType(), body.Bytes())
+
+	return nil
+}
+
+type Header struct{}
+
+func (Header) Name() string { return "header" }
+
+// header names are HTTP tokens, and the ones Go manages itself can't be injected
+func (Header) Accepts(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Host", "Content-Length", "Connection", "Transfer-Encoding":
+		return false
+	}
+
+	return isToken(name)
+}
+
+func (Header) Inject(req *http.Request, params []Param) error {
+	for _, param := range params {
+		req.Header.Set(param.Name, param.Value)
+	}
+
+	return nil
+}
+
+// Cookie adds the params to the Cookie header, after any cookies already set with -H
+type Cookie struct{}
+
+func (Cookie) Name() string { return "cookie" }
+
+// cookie names are HTTP tokens as well, anything else would break the Cookie header
+func (Cookie) Accepts(name string) bool { return isToken(name) }
+
+func (Cookie) Inject(req *http.Request, params []Param) error {
+	var cookies []string
+
+	if existing := strings.TrimSpace(req.Header.Get("Cookie")); existing != "" {
+		cookies = append(cookies, existing)
+	}
+
+	for _, param := range params {
+		cookies = append(cookies, fmt.Sprintf("%s=%s", param.Name, param.Value))
+	}
+
+	req.Header.Set("Cookie", strings.Join(cookies, "; "))
+
+	return nil
+}
+
+// Template replaces the whole request with the one built from a request template
+type Template struct {
+	Template *requesttemplate.Template
+}
+
+func (Template) Name() string { return "template" }
+
+// drops the names that can't be written where the template's marker is
+func (t Template) Accepts(name string) bool {
+	switch t.Template.Location {
+	case requesttemplate.LocationHeaderLines:
+		return Header{}.Accepts(name)
+	case requesttemplate.LocationCookie:
+		return Cookie{}.Accepts(name)
+	case requesttemplate.LocationXML:
+		return XML{}.Accepts(name)
+	}
+
+	return name != ""
+}
+
+func (t Template) Inject(req *http.Request, params []Param) error {
+	templateParams := make([]requesttemplate.Param, 0, len(params))
+
+	for _, param := range params {
+		templateParams = append(templateParams, requesttemplate.Param{Name: param.Name, Value: param.Value})
+	}
+
+	built, err := t.Template.NewRequest(req.URL.String(), templateParams)
+
+	if err != nil {
+		return err
+	}
+
+	req.Method = built.Method
+	req.URL = built.URL
+	req.Body = built.Body
+	req.GetBody = built.GetBody
+	req.ContentLength = built.ContentLength
+
+	for name, values := range built.Header {
+		req.Header[name] = values
+	}
+
+	return nil
+}
+
+func setBody(req *http.Request, contentType string, body []byte) {
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", contentType)
+}
+
+func xmlElement(name string, value string) string {
+	escaped := &strings.Builder{}
+	xml.EscapeText(escaped, []byte(value))
+
+	return fmt.Sprintf("<%s>%s</%s>", name, escaped.String(), name)
+}
+
+func isToken(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, c := range name {
+		if c > 127 || !strings.ContainsRune("!#$%&'*+-.^_`|~", c) && !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,217 @@
+package jsonscanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/michael1026/paramfinderSlimmed/detector"
+	"github.com/michael1026/paramfinderSlimmed/types/scan"
+	"golang.org/x/exp/slices"
+)
+
+// longer lists of differences are cut off in the finding's reason
+const maxReportedDiffs = 10
+
+type Detector struct{}
+
+func (Detector) Name() string {
+	return "json"
+}
+
+func (Detector) Detect(urlInfo *scan.URLInfo, resp *detector.Response) []scan.Finding {
+	if !IsJSON(urlInfo.ContentType) || urlInfo.Baseline.JSONShape == nil {
+		return nil
+	}
+
+	diffs := CheckShapeForDiff(resp.RawBody, urlInfo)
+
+	if len(diffs) == 0 {
+		return nil
+	}
+
+	if len(diffs) > maxReportedDiffs {
+		diffs = append(diffs[:maxReportedDiffs], fmt.Sprintf("%d more", len(diffs)-maxReportedDiffs))
+	}
+
+	return []scan.Finding{{Detector: "json", Reason: strings.Join(diffs, ", ")}}
+}
+
+func IsJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+/***********************************************************************
+*
+* Flattens a JSON document into a path for every value, mapped to its
+* type. Arrays also carry their length, and their elements are merged
+* under a single "[]" path so a longer list doesn't add new paths.
+*
+************************************************************************/
+
+func Shape(body string) (map[string]string, bool) {
+	var document interface{}
+
+	if err := json.Unmarshal([]byte(body), &document); err != nil {
+		return nil, false
+	}
+
+	shape := make(map[string]string)
+	addToShape(shape, "$", document)
+
+	return shape, true
+}
+
+func addToShape(shape map[string]string, path string, value interface{}) {
+	var valueType string
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		valueType = "object"
+
+		for key, child := range v {
+			addToShape(shape, path+"."+key, child)
+		}
+	case []interface{}:
+		valueType = fmt.Sprintf("array(%d)", len(v))
+
+		for _, child := range v {
+			addToShape(shape, path+"[]", child)
+		}
+	case string:
+		valueType = "string"
+	case float64:
+		valueType = "number"
+	case bool:
+		valueType = "bool"
+	default:
+		valueType = "null"
+	}
+
+	if existing, ok := shape[path]; ok && existing != valueType {
+		valueType = "mixed"
+	}
+
+	shape[path] = valueType
+}
+
+// the shape of the first sample, and the paths that aren't the same in every sample
+func NewShapeBaseline(bodies []string) (map[string]string, []string) {
+	if len(bodies) == 0 {
+		return nil, nil
+	}
+
+	baseline, ok := Shape(bodies[0])
+
+	if !ok {
+		return nil, nil
+	}
+
+	var dynamic []string
+
+	for _, body := range bodies[1:] {
+		shape, _ := Shape(body)
+
+		for _, path := range shapeDiffPaths(baseline, shape) {
+			if !slices.Contains(dynamic, path) {
+				dynamic = append(dynamic, path)
+			}
+		}
+	}
+
+	slices.Sort(dynamic)
+
+	return baseline, dynamic
+}
+
+func CheckShapeForDiff(body string, urlInfo *scan.URLInfo) []string {
+	var diffs []string
+
+	baseline := urlInfo.Baseline
+	shape, ok := Shape(body)
+
+	if !ok {
+		return []string{"response is no longer JSON"}
+	}
+
+	for _, path := range shapeDiffPaths(baseline.JSONShape, shape) {
+		if isDynamic(path, baseline.DynamicJSONPaths) {
+			continue
+		}
+
+		before, inBaseline := baseline.JSONShape[path]
+		after, inShape := shape[path]
+
+		switch {
+		case !inBaseline:
+			diffs = append(diffs, fmt.Sprintf("new key %s", path))
+		case !inShape:
+			diffs = append(diffs, fmt.Sprintf("missing key %s", path))
+		default:
+			diffs = append(diffs, fmt.Sprintf("%s %s -> %s", path, before, after))
+		}
+	}
+
+	return diffs
+}
+
+// names of every object key, used as potential parameters for JSON responses
+func Keys(body string) []string {
+	var keys []string
+
+	shape, ok := Shape(body)
+
+	if !ok {
+		return keys
+	}
+
+	for path := range shape {
+		if i := strings.LastIndex(path, "."); i >= 0 {
+			key := strings.TrimRight(path[i+1:], "[]")
+
+			if key != "" && !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	return keys
+}
+
+func shapeDiffPaths(a map[string]string, b map[string]string) []string {
+	var paths []string
+
+	for path, valueType := range a {
+		if b[path] != valueType {
+			paths = append(paths, path)
+		}
+	}
+
+	for path := range b {
+		if _, ok := a[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+
+	slices.Sort(paths)
+
+	return slices.Compact(paths)
+}
+
+// anything below a dynamic path is dynamic too
+func isDynamic(path string, dynamicPaths []string) bool {
+	for _, dynamic := range dynamicPaths {
+		if path == dynamic || strings.HasPrefix(path, dynamic+".") || strings.HasPrefix(path, dynamic+"[]") {
+			return true
+		}
+	}
+
+	return false
+}
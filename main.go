@@ -2,48 +2,84 @@ package main
 
 import (
 	"bufio"
-	"bytes"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
-	"net/url"
 	"os"
 	"regexp"
 	"strings"
 	"sync"
-
+	"time"
+
+	"github.com/michael1026/paramfinderSlimmed/bisector"
+	"github.com/michael1026/paramfinderSlimmed/cookiescanner"
+	"github.com/michael1026/paramfinderSlimmed/detector"
+	"github.com/michael1026/paramfinderSlimmed/diffscanner"
+	"github.com/michael1026/paramfinderSlimmed/domscanner"
+	"github.com/michael1026/paramfinderSlimmed/injection"
+	"github.com/michael1026/paramfinderSlimmed/jsonscanner"
+	"github.com/michael1026/paramfinderSlimmed/redirectscanner"
 	"github.com/michael1026/paramfinderSlimmed/reflectedscanner"
+	"github.com/michael1026/paramfinderSlimmed/requesttemplate"
 	"github.com/michael1026/paramfinderSlimmed/scanhttp"
+	"github.com/michael1026/paramfinderSlimmed/stability"
+	"github.com/michael1026/paramfinderSlimmed/timingscanner"
 	"github.com/michael1026/paramfinderSlimmed/types/args"
 	"github.com/michael1026/paramfinderSlimmed/types/scan"
 	"github.com/michael1026/paramfinderSlimmed/util"
 	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 
 	"github.com/PuerkitoBio/goquery"
 )
 
 type Request struct {
 	*http.Request
-	url string
+	url      string
+	location string
+	params   map[string]string
 }
 
 type Response struct {
-	doc *goquery.Document
-	url string
+	doc    *goquery.Document
+	body   string
+	target Target
+}
+
+// each URL is scanned once per method and injection point, results are kept per target
+type Target struct {
+	url      string
+	method   string
+	location string
 }
 
-type Body struct {
-	body string
-	url  string
+func (t Target) String() string {
+	return t.method + " " + t.url + " via " + t.location
+}
+
+func (t Target) injectionPoint() injection.Point {
+	for _, point := range injectionPoints[t.method] {
+		if point.Name() == t.location {
+			return point
+		}
+	}
+
+	for _, point := range sourcePoints {
+		if point.Name() == t.location {
+			return point
+		}
+	}
+
+	return nil
 }
 
 type FoundParameters struct {
-	parameters []string
-	url        string
-	method     string
+	findings []scan.Finding
+	url      string
+	method   string
 }
 
 var regexs = []*regexp.Regexp{
@@ -54,35 +90,138 @@ var regexs = []*regexp.Regexp{
 }
 
 var START_MAX_PARAMS = 25
-var results map[string]scan.URLInfo
+var MAX_PROBE_PARAMS = START_MAX_PARAMS * 16
+var results map[Target]scan.URLInfo
 var resultsMutex *sync.RWMutex
+var sourceBaselines map[Target]scan.URLInfo
+var baselineSamples int
 var wordlist map[string]struct{}
 var client *http.Client
 var headers args.HeaderArgs
+var injectionPoints map[string][]injection.Point
+
+// where a confirmed parameter is re-tested to find which sources the application reads it from
+var sourcePoints = []injection.Point{injection.Query{}, injection.Form{}, injection.JSON{}, injection.Cookie{}, injection.Header{}}
 
 /***************************************
 * Ideas....
 * Break into different detection types (reflected, extra headers, number of each tag, etc)
 * - Reflected done
+* - Response diff done
+* - Redirect target done
+* - Set-Cookie delta done
+* - Response timing done
+* - JSON structure done
+* - Number of each tag done
 * Check stability of each detection type for each URL - Done
-* Ability to disable certain checks
+* Ability to disable certain checks - Done
 * Check max URL length for each host - Done
 * Write JSON as program runs
 /***************************************/
 
 func main() {
 	scanInfo := scan.New()
-	results = make(map[string]scan.URLInfo)
+	results = make(map[Target]scan.URLInfo)
+	sourceBaselines = make(map[Target]scan.URLInfo)
 	resultsMutex = &sync.RWMutex{}
 
 	outputFile := flag.String("o", "", "File to output results to (.json)")
 	wordlistFile := flag.String("w", "", "Wordlist file")
-	requestMethod := flag.String("X", "GET", "Request method (default GET)")
+	requestMethods := flag.String("X", "GET", "Comma separated request methods, each URL is scanned once per method (default GET)")
+	encodings := flag.String("encodings", reflectedscanner.DefaultEncodings, "Comma separated encodings to look for when matching reflections")
+	samples := flag.Int("samples", 3, "Number of baseline requests per URL used to find dynamic content")
 	flag.Var(&headers, "H", "Headers to add")
+	mode := flag.String("mode", "params", "What to discover: params (query for GET, form body otherwise), query, form, json (JSON body keys), xml (XML body elements), multipart (multipart/form-data fields), headers (request header names from the wordlist) or cookies")
+	soapTemplateFile := flag.String("soap", "", "SOAP envelope template for xml mode, "+requesttemplate.Marker+" marks where the elements go")
+	alsoQuery := flag.Bool("query", false, "Also test candidates in the query string of non-GET requests")
+	templateFile := flag.String("template", "", "Raw request template, "+requesttemplate.Marker+" marks where the parameters go. Overrides -X and -mode")
 	// threads := flag.Int("t", 5, "Number of threads")
 
+	reflectionDetector := &reflectedscanner.Detector{}
+	headerDetector := &reflectedscanner.HeaderDetector{}
+
+	detectors := detector.NewRegistry()
+	detectors.Register(reflectionDetector, true)
+	detectors.Register(headerDetector, true)
+	detectors.Register(diffscanner.Detector{}, true)
+	detectors.Register(redirectscanner.Detector{}, true)
+	detectors.Register(cookiescanner.Detector{}, true)
+	detectors.Register(jsonscanner.Detector{}, true)
+	detectors.Register(domscanner.Detector{}, true)
+	// timing is noisy and needs more baseline samples, only run it when asked for
+	detectors.Register(timingscanner.Detector{}, false)
+
+	enableDetectors := flag.String("enable", "", fmt.Sprintf("Comma separated detectors to enable (%s)", strings.Join(detectors.Names(), ", ")))
+	disableDetectors := flag.String("disable", "", fmt.Sprintf("Comma separated detectors to disable (%s)", strings.Join(detectors.Names(), ", ")))
+
 	flag.Parse()
 
+	methods := parseMethods(*requestMethods)
+
+	if len(methods) == 0 {
+		log.Fatalf("At least one request method is required\n")
+	}
+
+	injectionPoints = make(map[string][]injection.Point)
+
+	for _, method := range methods {
+		point, err := newInjectionPoint(*mode, method, *soapTemplateFile, *templateFile)
+
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+
+		if template, ok := point.(injection.Template); ok {
+			// the template has its own method
+			methods = []string{template.Template.Method}
+			injectionPoints = map[string][]injection.Point{template.Template.Method: {point}}
+			break
+		}
+
+		if _, ok := point.(injection.Header); ok && *wordlistFile == "" {
+			log.Fatalf("A header wordlist (-w) is required in headers mode\n")
+		}
+
+		injectionPoints[method] = []injection.Point{point}
+
+		// some frameworks merge the query into the body parameters, others only read the query
+		if _, ok := point.(injection.Query); *alsoQuery && method != "GET" && !ok {
+			injectionPoints[method] = append(injectionPoints[method], injection.Query{})
+		}
+	}
+
+	if *samples < 1 {
+		log.Fatalf("At least one baseline sample is required\n")
+	}
+
+	matchEncodings, err := reflectedscanner.ParseEncodings(*encodings)
+
+	if err != nil {
+		log.Fatalf("Invalid encodings: %s\n", err)
+	}
+
+	if len(matchEncodings) == 0 {
+		log.Fatalf("At least one encoding is required\n")
+	}
+
+	reflectionDetector.Encodings = matchEncodings
+	headerDetector.Encodings = matchEncodings
+
+	if err := detectors.Enable(strings.Split(*enableDetectors, ",")); err != nil {
+		log.Fatalf("Unable to enable detectors: %s\n", err)
+	}
+
+	if err := detectors.Disable(strings.Split(*disableDetectors, ",")); err != nil {
+		log.Fatalf("Unable to disable detectors: %s\n", err)
+	}
+
+	if detectors.IsEnabled("timing") && *samples < timingscanner.MinSamples {
+		fmt.Printf("Timing detector enabled, taking %d baseline samples\n", timingscanner.MinSamples)
+		*samples = timingscanner.MinSamples
+	}
+
+	baselineSamples = *samples
+
 	if *wordlistFile != "" {
 		wordlist, _ = readWordlistIntoFile(*wordlistFile)
 		scanInfo.WordList = wordlist
@@ -98,45 +237,32 @@ func main() {
 	}
 
 	client = scanhttp.BuildHttpClient()
-	stabilityChannel := make(chan Request, len(lines))
-	stableChannel := make(chan string)
+	stabilityChannel := make(chan Request, len(lines)*len(methods))
+	stableChannel := make(chan Target)
 	stabilityRespChannel := make(chan Response)
 	sizeCheckReqChannel := make(chan Request)
-	readyToScanChannel := make(chan string)
+	readyToScanChannel := make(chan Target)
 	parameterURLChannel := make(chan Request)
-	parameterRespChannel := make(chan Body)
+	parameterRespChannel := make(chan *detector.Response)
 	foundParametersChannel := make(chan FoundParameters)
 	wg := sync.WaitGroup{}
 
-	if *requestMethod != "GET" {
-		// create requests
-		go addMethodURLsToStabilityRequestChannel(lines, stabilityChannel, *requestMethod)
-		// send requests and get responses (possible issue. Not all responses are needed to determine stability)
-		go getStabilityResponses(stabilityChannel, stabilityRespChannel)
-		// check the stability responses to determine stability
-		go checkURLStability(stabilityRespChannel, stableChannel)
-		go createMaxBodySizeRequests(stableChannel, sizeCheckReqChannel, *requestMethod)
-		go checkMaxReqSize(sizeCheckReqChannel, readyToScanChannel)
-		go createParameterReqs(readyToScanChannel, parameterURLChannel, *requestMethod)
-	} else {
-		// create requests
-		go addURLsToStabilityRequestChannel(lines, stabilityChannel)
-		// send requests and get responses (possible issue. Not all responses are needed to determine stability)
-		go getStabilityResponses(stabilityChannel, stabilityRespChannel)
-		// check the stability responses to determine stability
-		go checkURLStability(stabilityRespChannel, stableChannel)
-		// add stable URLs to channel for determining max URL size
-		go createMaxURLSizeRequests(stableChannel, sizeCheckReqChannel)
-		// check for max URL size
-		go checkMaxURLSize(sizeCheckReqChannel, readyToScanChannel)
-		// create URLs to find parameters
-		go createParameterURLs(readyToScanChannel, parameterURLChannel)
-	}
+	// create requests
+	go addURLsToStabilityRequestChannel(lines, stabilityChannel, methods)
+	// send requests and get responses (possible issue. Not all responses are needed to determine stability)
+	go getStabilityResponses(stabilityChannel, stabilityRespChannel)
+	// check the stability responses to determine stability
+	go checkURLStability(stabilityRespChannel, stableChannel)
+	// probe how many parameters each URL accepts at the injection point
+	go createMaxSizeRequests(stableChannel, sizeCheckReqChannel)
+	go checkMaxSize(sizeCheckReqChannel, readyToScanChannel)
+	// split the potential parameters into chunks of that size
+	go createParameterReqs(readyToScanChannel, parameterURLChannel)
 
 	// send requests to get responses
 	go getParameterResponses(parameterURLChannel, parameterRespChannel)
-	// check responses for reflections
-	go findReflections(parameterRespChannel, foundParametersChannel, *requestMethod)
+	// run the enabled detectors against each response
+	go findReflections(parameterRespChannel, foundParametersChannel, detectors.Enabled())
 
 	writeJsonResults(foundParametersChannel, *outputFile)
 
@@ -147,17 +273,36 @@ func writeJsonResults(foundParamsChan chan FoundParameters, outputFile string) {
 	jsonResults := make(map[string]scan.JsonResult)
 
 	for paramResult := range foundParamsChan {
+		var names []string
+
+		for _, finding := range paramResult.findings {
+			names = append(names, finding.Name)
+		}
+
 		if entry, ok := jsonResults[paramResult.url]; ok {
+			found := false
+
 			for i, entryParams := range entry.Params {
 				if paramResult.method == entryParams.Method {
-					entryParams.Names = append(entryParams.Names, paramResult.parameters...)
+					for _, name := range names {
+						if !slices.Contains(entryParams.Names, name) {
+							entryParams.Names = append(entryParams.Names, name)
+						}
+					}
+					entryParams.Findings = append(entryParams.Findings, paramResult.findings...)
+					entry.Params[i] = entryParams
+					found = true
 				}
-				entry.Params[i] = entryParams
+			}
+
+			if !found {
+				param := scan.Param{Method: paramResult.method, Names: names, Findings: paramResult.findings}
+				entry.Params = append(entry.Params, param)
 			}
 
 			jsonResults[paramResult.url] = entry
 		} else {
-			param := scan.Param{Method: paramResult.method, Names: paramResult.parameters}
+			param := scan.Param{Method: paramResult.method, Names: names, Findings: paramResult.findings}
 			result := scan.JsonResult{
 				Params: []scan.Param{param},
 			}
@@ -179,7 +324,7 @@ func writeJsonResults(foundParamsChan chan FoundParameters, outputFile string) {
 	}
 }
 
-func findReflections(parameterResponses chan Body, foundParamsChan chan FoundParameters, method string) {
+func findReflections(parameterResponses chan *detector.Response, foundParamsChan chan FoundParameters, detectors []detector.Detector) {
 	var wg sync.WaitGroup
 
 	for i := 0; i < 10; i++ {
@@ -188,18 +333,39 @@ func findReflections(parameterResponses chan Body, foundParamsChan chan FoundPar
 			defer wg.Done()
 
 			for resp := range parameterResponses {
-				if entry, ok := loadResults(resp.url); ok {
-					foundParams := reflectedscanner.CheckDocForReflections(resp.body, &entry)
+				target := Target{url: resp.URL, method: resp.Method, location: resp.InjectionPoint}
+
+				if entry, ok := loadResults(target); ok {
+					resp.Body = stability.Mask(resp.Body, entry.DynamicRegions)
 
-					if len(foundParams) > 0 {
-						for _, param := range foundParams {
-							fmt.Printf("Found \"%s\" on %s\n", param, resp.url)
+					for _, d := range detectors {
+						if !entry.Stable && !detector.IgnoresBaseline(d) {
+							continue
 						}
 
-						foundParamsChan <- FoundParameters{
-							url:        resp.url,
-							parameters: foundParams,
-							method:     method,
+						findings := runDetector(d, resp, &entry, target)
+
+						if len(findings) > 0 {
+							for i, finding := range findings {
+								findings[i].Location = target.location
+								findings[i].Confirmed = verifyFinding(d, finding, target, &entry)
+
+								if findings[i].Confirmed {
+									if encodings := probeEncodings(d, finding, target, &entry); len(encodings) > 0 {
+										findings[i].Encodings = encodings
+									}
+
+									findings[i].Sources = findSources(d, finding, target, &entry)
+								}
+
+								printFinding(findings[i], target)
+							}
+
+							foundParamsChan <- FoundParameters{
+								url:      resp.URL,
+								findings: findings,
+								method:   resp.Method,
+							}
 						}
 					}
 				}
@@ -211,7 +377,197 @@ func findReflections(parameterResponses chan Body, foundParamsChan chan FoundPar
 	close(foundParamsChan)
 }
 
-func getParameterResponses(parameterURLs chan Request, parameterResponses chan Body) {
+func printFinding(finding scan.Finding, target Target) {
+	details := finding.Detector
+
+	if len(finding.Headers) > 0 {
+		details += ": " + strings.Join(finding.Headers, ", ")
+	} else if len(finding.Contexts) > 0 {
+		details += ": " + strings.Join(finding.Contexts, ", ")
+	}
+
+	if finding.Confirmed {
+		details += ", confirmed"
+	}
+
+	if len(finding.Sources) > 1 {
+		details += ", read from " + strings.Join(finding.Sources, ", ")
+	}
+
+	fmt.Printf("Found \"%s\" on %s (%s)\n", finding.Name, target, details)
+}
+
+/***********************************************************************
+*
+* Re-requests a single finding on its own with a fresh value, then sends
+* a control request without it. The control is checked for the same
+* value, so a finding that comes from cache or shared state rather than
+* the parameter itself is not confirmed.
+*
+************************************************************************/
+
+func verifyFinding(d detector.Detector, finding scan.Finding, target Target, urlInfo *scan.URLInfo) bool {
+	params := map[string]string{finding.Name: util.RandSeq(10)}
+
+	resp, ok := getChunkResponse(target, params, urlInfo)
+
+	if !ok || !reportsParameter(d.Detect(urlInfo, resp), finding.Name) {
+		return false
+	}
+
+	controlResp, ok := getChunkResponse(target, map[string]string{}, urlInfo)
+
+	if !ok {
+		return false
+	}
+
+	controlResp.Parameters = params
+
+	return len(d.Detect(urlInfo, controlResp)) == 0
+}
+
+/***********************************************************************
+*
+* Chunk values are plain letters that most encodings leave unchanged.
+* For detectors that support it, one extra request with a value holding
+* characters the encodings transform finds the encodings that actually
+* matched. A server that escapes them differently or strips them gives
+* nothing back, the finding keeps the encodings it was found with.
+* Cookies and headers can't carry these characters, so they're skipped.
+*
+************************************************************************/
+
+func probeEncodings(d detector.Detector, finding scan.Finding, target Target, urlInfo *scan.URLInfo) []string {
+	prober, ok := d.(detector.EncodingProber)
+
+	if !ok || !carriesEncodingProbe(target.injectionPoint()) {
+		return nil
+	}
+
+	resp, ok := getChunkResponse(target, map[string]string{finding.Name: prober.EncodingProbeValue()}, urlInfo)
+
+	if !ok {
+		return nil
+	}
+
+	for _, probed := range d.Detect(urlInfo, resp) {
+		if probed.Name == finding.Name {
+			return probed.Encodings
+		}
+	}
+
+	return nil
+}
+
+func carriesEncodingProbe(point injection.Point) bool {
+	switch p := point.(type) {
+	case injection.Cookie, injection.Header:
+		return false
+	case injection.Template:
+		switch p.Template.Location {
+		case requesttemplate.LocationCookie, requesttemplate.LocationHeaderLines, requesttemplate.LocationHeaderValue:
+			return false
+		}
+	}
+
+	return true
+}
+
+/***********************************************************************
+*
+* Re-tests a confirmed finding at each of the source points the same
+* way it was verified, to tell a parameter read from one place apart
+* from one read from several (PHP's $_REQUEST, ASP.NET's Request[]).
+* Bodies are not sent with GET, like the body modes. Each source has
+* its own request shape, so it's compared against its own baseline.
+*
+************************************************************************/
+
+func findSources(d detector.Detector, finding scan.Finding, target Target, urlInfo *scan.URLInfo) []string {
+	sources := []string{target.location}
+
+	for _, point := range sourcePoints {
+		if point.Name() == target.location || !point.Accepts(finding.Name) {
+			continue
+		}
+
+		switch point.(type) {
+		case injection.Form, injection.JSON:
+			if target.method == "GET" {
+				continue
+			}
+		}
+
+		source := Target{url: target.url, method: target.method, location: point.Name()}
+		sourceInfo, ok := loadSourceBaseline(source)
+
+		if !ok || (!sourceInfo.Stable && !detector.IgnoresBaseline(d)) {
+			continue
+		}
+
+		if verifyFinding(d, finding, source, &sourceInfo) {
+			sources = append(sources, source.location)
+		}
+	}
+
+	return sources
+}
+
+// a chunk-level finding counts too, the only parameter sent is the one being checked
+func reportsParameter(findings []scan.Finding, name string) bool {
+	for _, finding := range findings {
+		if finding.Name == name || finding.Name == "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+/***********************************************************************
+*
+* Runs one detector against a chunk response. Findings that apply to the
+* whole chunk are bisected with the same detector until the responsible
+* parameters are found. Before splitting, a single made up parameter is
+* sent as a control. If the control triggers the detector too, any
+* parameter would have, so the chunk is treated as a false positive
+* without paying for the bisection.
+*
+************************************************************************/
+
+func runDetector(d detector.Detector, resp *detector.Response, urlInfo *scan.URLInfo, target Target) []scan.Finding {
+	var findings []scan.Finding
+
+	probe := func(params map[string]string) []scan.Finding {
+		chunkResp, ok := getChunkResponse(target, params, urlInfo)
+
+		if !ok {
+			return nil
+		}
+
+		return d.Detect(urlInfo, chunkResp)
+	}
+
+	for _, finding := range d.Detect(urlInfo, resp) {
+		if finding.Name != "" {
+			findings = append(findings, finding)
+			continue
+		}
+
+		if len(probe(map[string]string{util.RandSeq(8): util.RandSeq(10)})) > 0 {
+			fmt.Printf("%s triggers on any parameter for %s, treating chunk as a false positive\n", d.Name(), target)
+			continue
+		}
+
+		fmt.Printf("%s detected a change on %s (%s), splitting chunk of %d\n", d.Name(), target, finding.Reason, len(resp.Parameters))
+
+		findings = append(findings, bisector.Bisect(finding, resp.Parameters, probe)...)
+	}
+
+	return findings
+}
+
+func getParameterResponses(parameterURLs chan Request, parameterResponses chan *detector.Response) {
 	var wg sync.WaitGroup
 
 	for i := 0; i < 10; i++ {
@@ -220,6 +576,7 @@ func getParameterResponses(parameterURLs chan Request, parameterResponses chan B
 			defer wg.Done()
 
 			for req := range parameterURLs {
+				start := time.Now()
 				resp, err := client.Do(req.Request)
 
 				if err != nil {
@@ -228,12 +585,11 @@ func getParameterResponses(parameterURLs chan Request, parameterResponses chan B
 
 				defer resp.Body.Close()
 
-				bodyString := util.ResponseToBodyString(resp)
+				response := detector.NewResponse(req.url, req.params, resp)
+				response.Duration = time.Since(start)
+				response.InjectionPoint = req.location
 
-				parameterResponses <- Body{
-					body: bodyString,
-					url:  req.url,
-				}
+				parameterResponses <- response
 			}
 		}()
 	}
@@ -242,233 +598,302 @@ func getParameterResponses(parameterURLs chan Request, parameterResponses chan B
 	close(parameterResponses)
 }
 
-func createParameterURLs(readyToScanChannel chan string, parameterURLChannel chan Request) {
+func createParameterReqs(readyToScanChannel chan Target, parameterURLChannel chan Request) {
 	defer close(parameterURLChannel)
 
-	for rawUrl := range readyToScanChannel {
-		if entry, ok := loadResults(rawUrl); ok {
+	for target := range readyToScanChannel {
+		if entry, ok := loadResults(target); ok {
 			paramCount := 0
 			totalCount := 0
-
-			parsedUrl, err := url.Parse(rawUrl)
-
-			if err != nil {
-				continue
-			}
-
-			query := parsedUrl.Query()
+			chunk := make(map[string]string)
 
 			for name, value := range entry.PotentialParameters {
-				query.Add(name, value)
+				chunk[name] = value
 				paramCount++
 				totalCount++
 
 				if paramCount == entry.MaxParams || totalCount == len(entry.PotentialParameters) {
-					encodedQuery := query.Encode()
-					encodedQuery = fmt.Sprintf("%s=%s&%s", util.RandSeq(6), entry.CanaryValue, encodedQuery)
-					parsedUrl.RawQuery = encodedQuery
+					req := createChunkRequest(target, chunk, &entry)
 
-					parameterURLChannel <- Request{
-						url:     rawUrl,
-						Request: createRequest(parsedUrl.String(), "GET", nil),
+					if req == nil {
+						fmt.Printf("Error creating request for %s\n", target)
+						continue
 					}
 
-					paramCount = 0
-
-					parsedUrl, err := url.Parse(rawUrl)
-
-					if err != nil {
-						fmt.Printf("Error with parsing %s\n", err)
-						continue
+					parameterURLChannel <- Request{
+						url:      target.url,
+						location: target.location,
+						Request:  req,
+						params:   chunk,
 					}
 
-					query = parsedUrl.Query()
+					paramCount = 0
+					chunk = make(map[string]string)
 				}
 			}
 		}
 	}
 }
 
-func createParameterReqs(readyToScanChannel chan string, parameterURLChannel chan Request, method string) {
-	defer close(parameterURLChannel)
-
-	for rawUrl := range readyToScanChannel {
-		if entry, ok := loadResults(rawUrl); ok {
-			paramCount := 0
-			totalCount := 0
+/***********************************************************************
+*
+* Builds the request for one chunk at the injection point. The URL's
+* canary parameter is always added first, under the same name in every
+* request so it never shows up as a change of its own.
+*
+************************************************************************/
 
-			query := url.Values{}
+func createChunkRequest(target Target, chunk map[string]string, urlInfo *scan.URLInfo) *http.Request {
+	req := createRequest(target.url, target.method, nil)
 
-			for name, value := range entry.PotentialParameters {
-				query.Add(name, value)
-				paramCount++
-				totalCount++
+	if req == nil {
+		return nil
+	}
 
-				if paramCount == entry.MaxParams || totalCount == len(entry.PotentialParameters) {
-					encodedQuery := query.Encode()
-					encodedQuery = fmt.Sprintf("%s=%s&%s", util.RandSeq(6), entry.CanaryValue, encodedQuery)
-					req := createRequest(rawUrl, method, strings.NewReader(encodedQuery))
+	params := []injection.Param{{Name: urlInfo.CanaryName, Value: urlInfo.CanaryValue}}
 
-					parameterURLChannel <- Request{
-						url:     rawUrl,
-						Request: req,
-					}
+	names := maps.Keys(chunk)
+	slices.Sort(names)
 
-					paramCount = 0
+	for _, name := range names {
+		params = append(params, injection.Param{Name: name, Value: chunk[name]})
+	}
 
-					query = url.Values{}
-				}
-			}
-		}
+	if err := target.injectionPoint().Inject(req, params); err != nil {
+		fmt.Printf("Error injecting parameters into %s: %s\n", target, err)
+		return nil
 	}
+
+	return req
 }
 
-func checkURLStability(stabilityRespChannel chan Response, stableChannel chan string) {
-	defer close(stableChannel)
+/***********************************************************************
+*
+* Sends a single chunk outside of the main pipeline. Used when a chunk
+* has to be split and re-requested to find the responsible parameters.
+*
+************************************************************************/
 
-	for resp := range stabilityRespChannel {
-		if entry, ok := loadResults(resp.url); ok {
-			entry.PotentialParameters = findPotentialParameters(resp.doc)
+func getChunkResponse(target Target, chunk map[string]string, urlInfo *scan.URLInfo) (*detector.Response, bool) {
+	req := createChunkRequest(target, chunk, urlInfo)
 
-			stableChannel <- resp.url
+	if req == nil {
+		return nil, false
+	}
 
-			addToResults(resp.url, entry)
-		}
+	start := time.Now()
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return nil, false
 	}
-}
 
-func checkMaxURLSize(sizeCheckReqChannel chan Request, readyToScanURLs chan string) {
-	defer close(readyToScanURLs)
+	defer resp.Body.Close()
 
-	for req := range sizeCheckReqChannel {
-		if entry, ok := loadResults(req.url); ok {
-			currentMaxParams := len(req.Request.URL.Query()) - START_MAX_PARAMS
+	chunkResp := detector.NewResponse(target.url, chunk, resp)
+	chunkResp.Duration = time.Since(start)
+	chunkResp.InjectionPoint = target.location
+	chunkResp.Body = stability.Mask(chunkResp.Body, urlInfo.DynamicRegions)
 
-			if entry.MaxParams != START_MAX_PARAMS {
-				// already solved, move on.
-				continue
-			}
+	return chunkResp, true
+}
 
-			resp, err := client.Do(req.Request)
+func checkURLStability(stabilityRespChannel chan Response, stableChannel chan Target) {
+	defer close(stableChannel)
 
-			if err != nil || resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != entry.ContentType {
-				entry.MaxParams = currentMaxParams
-				readyToScanURLs <- req.url
-				addToResults(req.url, entry)
-				continue
+	for resp := range stabilityRespChannel {
+		target := resp.target
+		injectionPoint := target.injectionPoint()
+
+		if entry, ok := loadResults(target); ok {
+			if _, ok := injectionPoint.(injection.Header); ok {
+				// page content says nothing about request headers, only the wordlist is used
+				entry.PotentialParameters = wordlistParameters()
+			} else if jsonscanner.IsJSON(entry.ContentType) {
+				// parsing JSON as HTML finds nothing, use its keys instead
+				entry.PotentialParameters = jsonParameters(resp.body)
+			} else {
+				entry.PotentialParameters = findPotentialParameters(resp.doc)
+			}
+
+			for name := range entry.PotentialParameters {
+				if !injectionPoint.Accepts(name) {
+					delete(entry.PotentialParameters, name)
+				}
 			}
 
-			addToResults(req.url, entry)
+			stableChannel <- target
+
+			addToResults(target, entry)
 		}
 	}
 }
 
-func checkMaxReqSize(sizeCheckReqChannel chan Request, readyToScanReqs chan string) {
+/***********************************************************************
+*
+* Finds how many parameters a URL accepts in one request, using the same
+* request shape as the chunks. Probes grow by START_MAX_PARAMS until one
+* fails, the limit is the last size that worked. A probe fails when the
+* status code or Content-Type differ from the baseline. If even the
+* smallest probe fails, chunks stay at START_MAX_PARAMS.
+*
+************************************************************************/
+
+func checkMaxSize(sizeCheckReqChannel chan Request, readyToScanReqs chan Target) {
 	defer close(readyToScanReqs)
 
+	solved := make(map[Target]bool)
+
 	for req := range sizeCheckReqChannel {
-		if entry, ok := loadResults(req.url); ok {
-			data, err := ioutil.ReadAll(req.Body)
+		target := Target{url: req.url, method: req.Method, location: req.location}
 
-			if err != nil {
-				continue
-			}
+		if solved[target] {
+			continue
+		}
 
-			bodyParsed, err := url.ParseQuery(string(data))
+		if entry, ok := loadResults(target); ok {
+			resp, err := client.Do(req.Request)
 
-			if err != nil {
-				continue
+			if err == nil {
+				resp.Body.Close()
 			}
 
-			currentMaxParams := len(bodyParsed) - START_MAX_PARAMS
-
-			if entry.MaxParams != START_MAX_PARAMS {
-				// already solved, move on.
-				continue
-			}
+			if err != nil || resp.StatusCode != entry.Baseline.StatusCode || resp.Header.Get("Content-Type") != entry.ContentType {
+				entry.MaxParams = len(req.params) - START_MAX_PARAMS
 
-			resp, err := client.Do(req.Request)
-
-			if err != nil || resp.StatusCode != http.StatusOK {
-				entry.MaxParams = currentMaxParams
-				readyToScanReqs <- req.url
-				addToResults(req.url, entry)
+				if entry.MaxParams < START_MAX_PARAMS {
+					fmt.Printf("%s failed the smallest size probe, using chunks of %d\n", target, START_MAX_PARAMS)
+					entry.MaxParams = START_MAX_PARAMS
+				}
+			} else if len(req.params) >= MAX_PROBE_PARAMS {
+				entry.MaxParams = len(req.params)
+			} else {
 				continue
 			}
 
-			addToResults(req.url, entry)
+			solved[target] = true
+			addToResults(target, entry)
+			readyToScanReqs <- target
 		}
 	}
 }
 
-func createMaxURLSizeRequests(stableReqChannel chan string, sizeCheckReqChannel chan Request) {
+func createMaxSizeRequests(stableReqChannel chan Target, sizeCheckReqChannel chan Request) {
 	defer close(sizeCheckReqChannel)
 
-	for rawUrl := range stableReqChannel {
-		parsedUrl, err := url.Parse(rawUrl)
+	for target := range stableReqChannel {
+		if entry, ok := loadResults(target); ok {
+			params := make(map[string]string)
 
-		if err != nil {
-			fmt.Printf("Error parsing URL: %s\n", err)
-			continue
-		}
+			for len(params) < MAX_PROBE_PARAMS {
+				for i := 0; i < START_MAX_PARAMS; i++ {
+					params[util.RandSeq(10)] = util.RandSeq(10)
+				}
 
-		query := parsedUrl.Query()
+				probeParams := maps.Clone(params)
+				req := createChunkRequest(target, probeParams, &entry)
 
-		// add 25 parameters to URL as a start
-		for i := 0; i < START_MAX_PARAMS; i++ {
-			query.Set(util.RandSeq(7), util.RandSeq(7))
-		}
+				if req == nil {
+					continue
+				}
 
-		// add an additional 25 (15 times)
-		for i := 0; i < 15; i++ {
-			for i := 0; i < START_MAX_PARAMS; i++ {
-				query.Set(util.RandSeq(10), util.RandSeq(10))
+				sizeCheckReqChannel <- Request{
+					url:      target.url,
+					location: target.location,
+					Request:  req,
+					params:   probeParams,
+				}
 			}
+		}
+	}
+}
 
-			parsedUrl.RawQuery = query.Encode()
-			req, err := http.NewRequest("HEAD", parsedUrl.String(), nil)
+// -X takes a comma separated list, duplicates are dropped
+func parseMethods(list string) []string {
+	var methods []string
 
-			if err != nil {
-				continue
-			}
+	for _, method := range strings.Split(list, ",") {
+		method = strings.ToUpper(strings.TrimSpace(method))
 
-			sizeCheckReqChannel <- Request{
-				url:     rawUrl,
-				Request: req,
-			}
+		if method != "" && !slices.Contains(methods, method) {
+			methods = append(methods, method)
 		}
 	}
+
+	return methods
 }
 
-func createMaxBodySizeRequests(stableReqChannel chan string, sizeCheckReqChannel chan Request, method string) {
-	defer close(sizeCheckReqChannel)
+/***********************************************************************
+*
+* Picks the injection point for the -mode, -soap and -template flags.
+* A request template overrides the mode.
+*
+************************************************************************/
 
-	for rawUrl := range stableReqChannel {
-		query := url.Values{}
+func newInjectionPoint(mode string, method string, soapTemplateFile string, templateFile string) (injection.Point, error) {
+	if templateFile != "" {
+		raw, err := ioutil.ReadFile(templateFile)
 
-		// add 25 parameters to URL as a start
-		for i := 0; i < START_MAX_PARAMS; i++ {
-			query.Set(util.RandSeq(7), util.RandSeq(7))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read request template: %s", err)
 		}
 
-		// add an additional 25 (15 times)
-		for i := 0; i < 15; i++ {
-			for i := 0; i < START_MAX_PARAMS; i++ {
-				query.Set(util.RandSeq(10), util.RandSeq(10))
-			}
+		template, err := requesttemplate.Parse(string(raw))
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid request template: %s", err)
+		}
+
+		return injection.Template{Template: template}, nil
+	}
 
-			req, err := http.NewRequest(method, rawUrl, bytes.NewBufferString(query.Encode()))
+	var point injection.Point
+
+	switch mode {
+	case "params":
+		if method == "GET" {
+			return injection.Query{}, nil
+		}
+		point = injection.Form{}
+	case "query":
+		return injection.Query{}, nil
+	case "headers":
+		return injection.Header{}, nil
+	case "cookies":
+		return injection.Cookie{}, nil
+	case "form":
+		point = injection.Form{}
+	case "json":
+		point = injection.JSON{}
+	case "multipart":
+		point = injection.Multipart{}
+	case "xml":
+		xmlPoint := injection.XML{}
+
+		if soapTemplateFile != "" {
+			soapTemplate, err := ioutil.ReadFile(soapTemplateFile)
 
 			if err != nil {
-				continue
+				return nil, fmt.Errorf("unable to read SOAP template: %s", err)
 			}
 
-			sizeCheckReqChannel <- Request{
-				url:     rawUrl,
-				Request: req,
+			if !strings.Contains(string(soapTemplate), requesttemplate.Marker) {
+				return nil, fmt.Errorf("SOAP template has no %s marker", requesttemplate.Marker)
 			}
+
+			xmlPoint.SoapTemplate = string(soapTemplate)
 		}
+
+		point = xmlPoint
+	default:
+		return nil, fmt.Errorf("unknown mode %s", mode)
 	}
+
+	if method == "GET" {
+		return nil, fmt.Errorf("%s mode sends a request body, use -X to pick a method such as POST", mode)
+	}
+
+	return point, nil
 }
 
 func createRequest(url string, method string, body io.Reader) *http.Request {
@@ -516,57 +941,37 @@ func readWordlistIntoFile(wordlistPath string) (map[string]struct{}, error) {
 	return lines, err
 }
 
-func addURLsToStabilityRequestChannel(urls []string, reqChan chan Request) {
+func addURLsToStabilityRequestChannel(urls []string, reqChan chan Request, methods []string) {
 	defer close(reqChan)
 
 	for _, rawUrl := range urls {
-		canary := util.RandSeq(6)
-		addToResults(rawUrl, scan.URLInfo{
-			CanaryValue: canary,
-			CanaryCount: 0,
-			Stable:      true,
-			MaxParams:   START_MAX_PARAMS,
-		})
+		for _, method := range methods {
+			for _, point := range injectionPoints[method] {
+				target := Target{url: rawUrl, method: method, location: point.Name()}
+				entry := newURLInfo()
+				addToResults(target, entry)
 
-		originalTestUrl, err := url.Parse(rawUrl)
+				// the baseline carries the canary parameter so it matches the shape of the chunk requests
+				req := createChunkRequest(target, nil, &entry)
 
-		if err != nil {
-			fmt.Printf("Error parsing URL: %s\n", err)
-			continue
-		}
-
-		query := originalTestUrl.Query()
-		originalTestUrl.RawQuery = query.Encode()
-
-		req := createRequest(originalTestUrl.String(), "GET", nil)
+				if req == nil {
+					fmt.Printf("Error creating request for %s\n", target)
+					continue
+				}
 
-		reqChan <- Request{req, rawUrl}
+				reqChan <- Request{Request: req, url: rawUrl, location: target.location}
+			}
+		}
 	}
 }
 
-func addMethodURLsToStabilityRequestChannel(urls []string, reqChan chan Request, method string) {
-	defer close(reqChan)
-
-	for _, rawUrl := range urls {
-		canary := util.RandSeq(6)
-		addToResults(rawUrl, scan.URLInfo{
-			CanaryValue: canary,
-			CanaryCount: 0,
-			Stable:      true,
-			MaxParams:   START_MAX_PARAMS,
-		})
-
-		originalTestUrl, err := url.Parse(rawUrl)
-
-		if err != nil {
-			fmt.Printf("Error parsing URL: %s\n", err)
-			continue
-		}
-
-		query := url.Values{}
-		req := createRequest(originalTestUrl.String(), method, strings.NewReader(query.Encode()))
-
-		reqChan <- Request{req, rawUrl}
+func newURLInfo() scan.URLInfo {
+	return scan.URLInfo{
+		CanaryName:  util.RandSeq(6),
+		CanaryValue: util.RandSeq(6),
+		CanaryCount: 0,
+		Stable:      true,
+		MaxParams:   START_MAX_PARAMS,
 	}
 }
 
@@ -579,37 +984,37 @@ func getStabilityResponses(requests chan Request, responses chan Response) {
 			defer wg.Done()
 
 			for req := range requests {
-				if entry, ok := loadResults(req.url); ok {
+				target := Target{url: req.url, method: req.Method, location: req.location}
+
+				if entry, ok := loadResults(target); ok {
 					if entry.Stable == false {
-						fmt.Printf("%s is unstable. Skipping.\n", req.url)
+						fmt.Printf("%s is unstable. Skipping.\n", target)
 						continue
 					}
 
-					resp, err := client.Do(req.Request)
+					samples := getStabilitySamples(req, target, baselineSamples, &entry)
 
-					if err != nil {
-						fmt.Printf("%s is unstable. Skipping.\n", req.url)
+					if len(samples) < baselineSamples {
+						fmt.Printf("%s is unstable. Skipping.\n", target)
 						entry.Stable = false
-						addToResults(req.url, entry)
+						addToResults(target, entry)
 						continue
 					}
 
-					defer resp.Body.Close()
+					setBaseline(target, &entry, samples)
 
-					if entry.ContentType == "" {
-						entry.ContentType = resp.Header.Get("Content-Type")
-					}
+					// store the baseline before handing off, checkURLStability loads and saves this entry too
+					addToResults(target, entry)
 
-					doc, err := goquery.NewDocumentFromResponse(resp)
+					doc, err := goquery.NewDocumentFromReader(strings.NewReader(samples[0].Body))
 
 					if err == nil && doc != nil {
 						responses <- Response{
-							url: req.url,
-							doc: doc,
+							target: target,
+							doc:    doc,
+							body:   samples[0].Body,
 						}
 					}
-
-					addToResults(req.url, entry)
 				}
 			}
 		}()
@@ -619,6 +1024,160 @@ func getStabilityResponses(requests chan Request, responses chan Response) {
 	close(responses)
 }
 
+/***********************************************************************
+*
+* Works out the dynamic regions and everything the detectors compare
+* against from a target's baseline samples.
+*
+************************************************************************/
+
+func setBaseline(target Target, entry *scan.URLInfo, samples []stability.Sample) {
+	dynamicRegions, stable := stability.Analyze(samples)
+
+	if !stable {
+		// noisy pages are still scanned, only by the detectors that don't compare against the baseline
+		fmt.Printf("%s is unstable. Only scanning for reflections.\n", target)
+		entry.Stable = false
+	}
+
+	if entry.ContentType == "" {
+		entry.ContentType = samples[0].Header.Get("Content-Type")
+	}
+
+	// the canary is removed the same way diffscanner removes it from chunk responses
+	maskedBody := stability.Mask(samples[0].Body, dynamicRegions)
+	maskedBody = strings.ReplaceAll(maskedBody, entry.CanaryValue, "")
+
+	entry.DynamicRegions = dynamicRegions
+	entry.Baseline = diffscanner.NewBaseline(samples[0].StatusCode, samples[0].Header, maskedBody)
+	entry.Baseline.DynamicHeaders = stability.DynamicHeaders(samples)
+	entry.Baseline.Cookies = cookiescanner.Cookies(samples[0].Header)
+	entry.Baseline.DynamicCookies = cookiescanner.DynamicCookies(sampleHeaders(samples))
+	entry.Baseline.LatencyMean, entry.Baseline.LatencyStdDev = timingscanner.Latency(sampleDurations(samples))
+
+	if jsonscanner.IsJSON(entry.ContentType) {
+		entry.Baseline.JSONShape, entry.Baseline.DynamicJSONPaths = jsonscanner.NewShapeBaseline(sampleBodies(samples))
+	} else if dom, ok := domscanner.NewDOM(maskedBody); ok {
+		entry.Baseline.DOM = dom
+	}
+}
+
+/***********************************************************************
+*
+* Baselines for the source points findSources re-tests at, sampled the
+* first time a URL needs one and kept for its other findings. False
+* when the baseline requests fail.
+*
+************************************************************************/
+
+func loadSourceBaseline(source Target) (scan.URLInfo, bool) {
+	resultsMutex.RLock()
+	entry, ok := sourceBaselines[source]
+	resultsMutex.RUnlock()
+
+	if ok {
+		return entry, entry.Baseline.StatusCode != 0
+	}
+
+	entry = newURLInfo()
+	req := createChunkRequest(source, nil, &entry)
+	samples := getStabilitySamples(Request{Request: req, url: source.url, location: source.location}, source, baselineSamples, &entry)
+
+	if len(samples) == baselineSamples {
+		setBaseline(source, &entry, samples)
+	}
+
+	resultsMutex.Lock()
+	sourceBaselines[source] = entry
+	resultsMutex.Unlock()
+
+	return entry, entry.Baseline.StatusCode != 0
+}
+
+/***********************************************************************
+*
+* Sends the same baseline request several times. Stops at the first
+* failed request, so fewer samples than requested means the URL is
+* unreliable.
+*
+************************************************************************/
+
+func getStabilitySamples(req Request, target Target, sampleCount int, urlInfo *scan.URLInfo) []stability.Sample {
+	var samples []stability.Sample
+
+	sampleReq := req.Request
+
+	for i := 0; i < sampleCount; i++ {
+		if i > 0 {
+			sampleReq = createChunkRequest(target, nil, urlInfo)
+		}
+
+		if sampleReq == nil {
+			break
+		}
+
+		start := time.Now()
+		resp, err := client.Do(sampleReq)
+
+		if err != nil {
+			break
+		}
+
+		body := util.ResponseToBodyString(resp)
+
+		samples = append(samples, stability.Sample{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+			Body:       body,
+			Duration:   time.Since(start),
+		})
+
+		resp.Body.Close()
+	}
+
+	return samples
+}
+
+func sampleHeaders(samples []stability.Sample) []http.Header {
+	var sampleHeaders []http.Header
+
+	for _, sample := range samples {
+		sampleHeaders = append(sampleHeaders, sample.Header)
+	}
+
+	return sampleHeaders
+}
+
+func sampleBodies(samples []stability.Sample) []string {
+	var bodies []string
+
+	for _, sample := range samples {
+		bodies = append(bodies, sample.Body)
+	}
+
+	return bodies
+}
+
+func sampleDurations(samples []stability.Sample) []time.Duration {
+	var durations []time.Duration
+
+	for _, sample := range samples {
+		durations = append(durations, sample.Duration)
+	}
+
+	return durations
+}
+
+func wordlistParameters() map[string]string {
+	parameters := make(map[string]string)
+
+	for name := range wordlist {
+		parameters[name] = util.RandSeq(10)
+	}
+
+	return parameters
+}
+
 /***********************************************************************
 *
 * Used to find possible parameter names by looking at the page source
@@ -643,6 +1202,18 @@ func findPotentialParameters(doc *goquery.Document) map[string]string {
 	return parameters
 }
 
+func jsonParameters(body string) map[string]string {
+	parameters := wordlistParameters()
+
+	for _, key := range jsonscanner.Keys(body) {
+		if len(key) <= 15 {
+			parameters[key] = util.RandSeq(10)
+		}
+	}
+
+	return parameters
+}
+
 /***********************************************************************
 *
 * Finds keywords by using some regex against the page source
@@ -677,13 +1248,13 @@ func keywordsFromRegex(doc *goquery.Document) []string {
 	return maps.Keys(newWordlist)
 }
 
-func addToResults(key string, info scan.URLInfo) {
+func addToResults(key Target, info scan.URLInfo) {
 	resultsMutex.Lock()
 	results[key] = info
 	resultsMutex.Unlock()
 }
 
-func loadResults(key string) (value scan.URLInfo, ok bool) {
+func loadResults(key Target) (value scan.URLInfo, ok bool) {
 	resultsMutex.Lock()
 	result, ok := results[key]
 	resultsMutex.Unlock()
@@ -0,0 +1,89 @@
+package redirectscanner
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/michael1026/paramfinderSlimmed/detector"
+	"github.com/michael1026/paramfinderSlimmed/types/scan"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+type Detector struct{}
+
+func (Detector) Name() string {
+	return "redirect"
+}
+
+/***********************************************************************
+*
+* The client doesn't follow redirects, so the redirect status and
+* Location of each chunk can be compared against the baseline. A value
+* that lands in Location is reported by name as an open redirect
+* candidate, any other change to the redirect is left for bisection.
+*
+************************************************************************/
+
+func (Detector) Detect(urlInfo *scan.URLInfo, resp *detector.Response) []scan.Finding {
+	var findings []scan.Finding
+
+	location := resp.Header.Get("Location")
+	names := maps.Keys(resp.Parameters)
+	slices.Sort(names)
+
+	// a baseline Location holding the canary echoes the whole query, every value would land in it
+	echoesQuery := strings.Contains(urlInfo.Baseline.Location, urlInfo.CanaryValue)
+
+	for _, name := range names {
+		if value := resp.Parameters[name]; value != "" && !echoesQuery && strings.Contains(location, value) {
+			findings = append(findings, scan.Finding{
+				Name:     name,
+				Detector: "redirect",
+				Reason:   "value lands in Location, open redirect candidate",
+				Headers:  []string{"Location"},
+			})
+		}
+	}
+
+	if len(findings) > 0 {
+		return findings
+	}
+
+	if diff := CheckRedirectForDiff(resp.StatusCode, resp.Header, resp.Parameters, urlInfo); diff != "" {
+		return []scan.Finding{{Detector: "redirect", Reason: diff}}
+	}
+
+	return nil
+}
+
+func IsRedirect(statusCode int) bool {
+	return statusCode >= 300 && statusCode < 400
+}
+
+// the canary and sent values are removed so an echoed query doesn't count as a new target
+func CheckRedirectForDiff(statusCode int, header http.Header, params map[string]string, urlInfo *scan.URLInfo) string {
+	baseline := urlInfo.Baseline
+
+	if IsRedirect(statusCode) != IsRedirect(baseline.StatusCode) {
+		return fmt.Sprintf("redirect status %d -> %d", baseline.StatusCode, statusCode)
+	}
+
+	if slices.Contains(baseline.DynamicHeaders, "Location") {
+		return ""
+	}
+
+	location := strings.ReplaceAll(header.Get("Location"), urlInfo.CanaryValue, "")
+	baselineLocation := strings.ReplaceAll(baseline.Location, urlInfo.CanaryValue, "")
+
+	for _, value := range params {
+		location = strings.ReplaceAll(location, value, "")
+	}
+
+	if location != baselineLocation {
+		return fmt.Sprintf("Location %q -> %q", baselineLocation, location)
+	}
+
+	return ""
+}
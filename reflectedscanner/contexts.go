@@ -0,0 +1,177 @@
+package reflectedscanner
+
+import (
+	"net/http"
+	"strings"
+	"unicode"
+
+	"golang.org/x/exp/slices"
+)
+
+const (
+	ContextHTMLText          = "html-text"
+	ContextAttributeQuoted   = "attribute-quoted"
+	ContextAttributeUnquoted = "attribute-unquoted"
+	ContextTag               = "tag"
+	ContextScript            = "script"
+	ContextJSString          = "js-string"
+	ContextHTMLComment       = "html-comment"
+	ContextJSONString        = "json-string"
+	ContextResponseHeader    = "response-header"
+)
+
+/***********************************************************************
+*
+* Classifies every place a value was reflected in the body. Each
+* occurrence is looked at on its own, so a value reflected twice can
+* land in more than one context. Header reflections are reported by
+* HeaderDetector instead.
+*
+************************************************************************/
+
+func ClassifyContexts(body string, header http.Header, value string) []string {
+	var contexts []string
+
+	add := func(context string) {
+		if !slices.Contains(contexts, context) {
+			contexts = append(contexts, context)
+		}
+	}
+
+	isJSON := strings.Contains(header.Get("Content-Type"), "json")
+	offset := 0
+
+	for {
+		index := strings.Index(body[offset:], value)
+
+		if index < 0 {
+			break
+		}
+
+		index += offset
+		offset = index + len(value)
+
+		if isJSON {
+			if inQuotedString(body[:index], "\"") {
+				add(ContextJSONString)
+			}
+			continue
+		}
+
+		add(classifyHTMLContext(body[:index]))
+	}
+
+	return contexts
+}
+
+func classifyHTMLContext(before string) string {
+	if strings.LastIndex(before, "<!--") > strings.LastIndex(before, "-->") {
+		return ContextHTMLComment
+	}
+
+	scriptStart := lastIndexFold(before, "<script")
+
+	if scriptStart > lastIndexFold(before, "</script") {
+		scriptBody := before[scriptStart:]
+
+		if tagEnd := strings.Index(scriptBody, ">"); tagEnd >= 0 {
+			if inQuotedString(scriptBody[tagEnd+1:], "\"'`") {
+				return ContextJSString
+			}
+
+			return ContextScript
+		}
+	}
+
+	tagStart := strings.LastIndex(before, "<")
+
+	if tagStart > strings.LastIndex(before, ">") {
+		return classifyTagContext(before[tagStart:])
+	}
+
+	return ContextHTMLText
+}
+
+func classifyTagContext(tag string) string {
+	var quote rune
+	var last rune
+
+	for _, c := range tag {
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+		} else if (c == '"' || c == '\'') && last == '=' {
+			quote = c
+		}
+
+		if !unicode.IsSpace(c) {
+			last = c
+		}
+	}
+
+	if quote != 0 {
+		return ContextAttributeQuoted
+	}
+
+	trailing := tag[strings.LastIndexAny(tag, " \t\r\n")+1:]
+
+	if last == '=' || strings.Contains(trailing, "=") {
+		return ContextAttributeUnquoted
+	}
+
+	return ContextTag
+}
+
+// like strings.LastIndex but ignoring ASCII case. Offsets stay valid in s,
+// lowercasing the whole body can change its length (the Kelvin sign, invalid UTF-8)
+func lastIndexFold(s string, substr string) int {
+	for i := len(s) - len(substr); i >= 0; i-- {
+		if asciiEqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func asciiEqualFold(a string, b string) bool {
+	for i := 0; i < len(a); i++ {
+		if asciiLower(a[i]) != asciiLower(b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func asciiLower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+
+	return c
+}
+
+// reports whether the end of s is inside a string opened by one of quotes
+func inQuotedString(s string, quotes string) bool {
+	var open rune
+	escaped := false
+
+	for _, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case open != 0:
+			if c == open {
+				open = 0
+			}
+		case strings.ContainsRune(quotes, c):
+			open = c
+		}
+	}
+
+	return open != 0
+}
@@ -0,0 +1,62 @@
+package reflectedscanner
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/michael1026/paramfinderSlimmed/detector"
+	"github.com/michael1026/paramfinderSlimmed/types/scan"
+	"golang.org/x/exp/slices"
+)
+
+func TestClassifyContexts(t *testing.T) {
+	html := http.Header{"Content-Type": []string{"text/html"}}
+	json := http.Header{"Content-Type": []string{"application/json"}}
+
+	tests := []struct {
+		name   string
+		body   string
+		header http.Header
+		want   []string
+	}{
+		{"text", "<p>hello zzvalue</p>", html, []string{ContextHTMLText}},
+		{"quoted attribute", `<a href="/x?zzvalue">`, html, []string{ContextAttributeQuoted}},
+		{"unquoted attribute", `<input value=zzvalue>`, html, []string{ContextAttributeUnquoted}},
+		{"tag", `<input zzvalue>`, html, []string{ContextTag}},
+		{"script", `<script>var a = zzvalue;</script>`, html, []string{ContextScript}},
+		{"uppercase script", `<SCRIPT>var a = zzvalue;</SCRIPT>`, html, []string{ContextScript}},
+		{"js string", `<script>var a = "zzvalue";</script>`, html, []string{ContextJSString}},
+		{"after script", `<script>x</script><p>zzvalue</p>`, html, []string{ContextHTMLText}},
+		{"comment", `<!-- zzvalue -->`, html, []string{ContextHTMLComment}},
+		{"json string", `{"a": "zzvalue"}`, json, []string{ContextJSONString}},
+		{"twice", `<p>zzvalue</p><script>zzvalue</script>`, html, []string{ContextHTMLText, ContextScript}},
+		// these change length when lowercased, offsets into the body have to stay valid
+		{"kelvin sign", strings.Repeat("K", 10) + "<script>zzvalue</script>", html, []string{ContextScript}},
+		{"invalid utf-8", "\xff\xfe<SCRIPT>zzvalue</SCRIPT>", html, []string{ContextScript}},
+		{"kelvin sign before text", strings.Repeat("K", 10) + "<script></script><p>zzvalue</p>", html, []string{ContextHTMLText}},
+	}
+
+	for _, test := range tests {
+		got := ClassifyContexts(test.body, test.header, "zzvalue")
+
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDetectKelvinSignBeforeReflection(t *testing.T) {
+	urlInfo := &scan.URLInfo{CanaryValue: "canary"}
+	resp := &detector.Response{
+		Header:     http.Header{"Content-Type": []string{"text/html"}},
+		RawBody:    strings.Repeat("K", 10) + "<p>abcdefghij</p>",
+		Parameters: map[string]string{"q": "abcdefghij"},
+	}
+
+	findings := Detector{Encodings: []string{"raw"}}.Detect(urlInfo, resp)
+
+	if len(findings) != 1 || findings[0].Name != "q" || !slices.Equal(findings[0].Contexts, []string{ContextHTMLText}) {
+		t.Errorf("got %+v", findings)
+	}
+}
@@ -0,0 +1,94 @@
+package reflectedscanner
+
+import (
+	"net/url"
+	"strings"
+)
+
+// characters that end a URL embedded in a page
+const urlBoundaries = " \t\r\n\"'<>`"
+
+/***********************************************************************
+*
+* Pages that echo the whole request URL (canonical links, og:url, login
+* redirects) make every value look reflected. An echo is found by
+* looking for the first query pair, which is always the canary, and
+* widening it to the surrounding URL. It only counts as an echo when
+* other values from the query show up in that URL as well. Echoed URLs
+* are removed so reflections elsewhere can still be counted.
+*
+************************************************************************/
+
+func StripEchoedURLs(body string, rawQuery string) (string, bool) {
+	firstPair := strings.SplitN(rawQuery, "&", 2)[0]
+	name, value, ok := strings.Cut(firstPair, "=")
+
+	if !ok || name == "" || value == "" {
+		return body, false
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+
+	if err != nil || len(query) < 2 {
+		return body, false
+	}
+
+	var values []string
+
+	for _, queryValues := range query {
+		values = append(values, queryValues...)
+	}
+
+	anchors := []string{firstPair, name + "%3D" + value, name + "%3d" + value}
+	echoed := false
+
+	for _, anchor := range anchors {
+		offset := 0
+
+		for {
+			index := strings.Index(body[offset:], anchor)
+
+			if index < 0 {
+				break
+			}
+
+			index += offset
+			start, end := urlBounds(body, index, index+len(anchor))
+
+			if countContained(body[start:end], values) < 2 {
+				offset = index + len(anchor)
+				continue
+			}
+
+			body = body[:start] + body[end:]
+			offset = start
+			echoed = true
+		}
+	}
+
+	return body, echoed
+}
+
+func urlBounds(body string, start int, end int) (int, int) {
+	for start > 0 && !strings.ContainsRune(urlBoundaries, rune(body[start-1])) {
+		start--
+	}
+
+	for end < len(body) && !strings.ContainsRune(urlBoundaries, rune(body[end])) {
+		end++
+	}
+
+	return start, end
+}
+
+func countContained(s string, values []string) int {
+	count := 0
+
+	for _, value := range values {
+		if value != "" && strings.Contains(s, value) {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,85 @@
+package reflectedscanner
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+
+	"github.com/michael1026/paramfinderSlimmed/util"
+)
+
+const DefaultEncodings = "raw,url,html,json,base64,upper"
+
+// url, html and json all transform these, RandSeq values pass through them unchanged
+const EncodingProbe = "\"'<&"
+
+// probe values end with EncodingProbe so the encodings that matched can be recorded
+func EncodingProbeValue() string {
+	return util.RandSeq(10) + EncodingProbe
+}
+
+var encoders = map[string]func(string) string{
+	"raw":    func(value string) string { return value },
+	"url":    url.QueryEscape,
+	"html":   html.EscapeString,
+	"json":   jsonEscape,
+	"base64": func(value string) string { return base64.StdEncoding.EncodeToString([]byte(value)) },
+	"upper":  strings.ToUpper,
+}
+
+func ParseEncodings(list string) ([]string, error) {
+	var encodings []string
+
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+
+		if name == "" {
+			continue
+		}
+
+		if _, ok := encoders[name]; !ok {
+			return nil, fmt.Errorf("unknown encoding %q", name)
+		}
+
+		encodings = append(encodings, name)
+	}
+
+	return encodings, nil
+}
+
+/***********************************************************************
+*
+* Returns every transformed form of a value for the given encodings.
+* Encodings that leave the value unchanged are dropped unless they are
+* "raw", so the same form isn't counted twice.
+*
+************************************************************************/
+
+func EncodedForms(value string, encodings []string) map[string]string {
+	forms := make(map[string]string)
+
+	for _, name := range encodings {
+		encoded := encoders[name](value)
+
+		if name != "raw" && encoded == value {
+			continue
+		}
+
+		forms[name] = encoded
+	}
+
+	return forms
+}
+
+func jsonEscape(value string) string {
+	encoded, err := json.Marshal(value)
+
+	if err != nil {
+		return value
+	}
+
+	return string(encoded[1 : len(encoded)-1])
+}
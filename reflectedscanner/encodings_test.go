@@ -0,0 +1,35 @@
+package reflectedscanner
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/michael1026/paramfinderSlimmed/detector"
+	"github.com/michael1026/paramfinderSlimmed/types/scan"
+	"golang.org/x/exp/slices"
+)
+
+func TestEncodingProbeValue(t *testing.T) {
+	value := EncodingProbeValue()
+
+	for _, encoding := range []string{"url", "html", "json"} {
+		forms := EncodedForms(value, []string{encoding})
+
+		if _, ok := forms[encoding]; !ok {
+			t.Errorf("%s: probe value isn't transformed", encoding)
+			continue
+		}
+
+		resp := &detector.Response{
+			Header:     http.Header{"Content-Type": []string{"text/html"}},
+			RawBody:    "<p>" + forms[encoding] + "</p>",
+			Parameters: map[string]string{"q": value},
+		}
+
+		findings := Detector{Encodings: []string{"raw", "url", "html", "json"}}.Detect(&scan.URLInfo{CanaryValue: "canary"}, resp)
+
+		if len(findings) != 1 || !slices.Equal(findings[0].Encodings, []string{encoding}) {
+			t.Errorf("%s: got %+v", encoding, findings)
+		}
+	}
+}
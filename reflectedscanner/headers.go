@@ -0,0 +1,97 @@
+package reflectedscanner
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/michael1026/paramfinderSlimmed/detector"
+	"github.com/michael1026/paramfinderSlimmed/types/scan"
+	"golang.org/x/exp/slices"
+)
+
+// HeaderDetector reports values reflected into response headers. Header
+// injection is a separate bug class, so these are kept apart from body
+// reflections.
+type HeaderDetector struct {
+	Encodings []string
+}
+
+func (HeaderDetector) Name() string {
+	return "header"
+}
+
+func (HeaderDetector) IgnoresBaseline() bool {
+	return true
+}
+
+func (HeaderDetector) EncodingProbeValue() string {
+	return EncodingProbeValue()
+}
+
+func (d HeaderDetector) Detect(urlInfo *scan.URLInfo, resp *detector.Response) []scan.Finding {
+	var findings []scan.Finding
+
+	foundParameters := CheckHeadersForReflections(resp.Header, resp.RawQuery, resp.Parameters, urlInfo, encodingsOrRaw(d.Encodings))
+
+	if len(foundParameters) > MaxReflectedParameters {
+		return []scan.Finding{{Detector: "header", Reason: "too many reflected parameters"}}
+	}
+
+	for param, reflection := range foundParameters {
+		findings = append(findings, scan.Finding{
+			Name:      param,
+			Detector:  "header",
+			Contexts:  []string{ContextResponseHeader},
+			Headers:   reflection.Headers,
+			Encodings: reflection.Encodings,
+		})
+	}
+
+	return findings
+}
+
+/***********************************************************************
+*
+* Counts reflections per header, compared against the canary count of
+* that same header. Returns the headers each parameter was reflected in
+* and the encodings that matched.
+*
+************************************************************************/
+
+type HeaderReflection struct {
+	Headers   []string
+	Encodings []string
+}
+
+func CheckHeadersForReflections(header http.Header, rawQuery string, params map[string]string, urlInfo *scan.URLInfo, encodings []string) map[string]HeaderReflection {
+	foundParameters := make(map[string]HeaderReflection)
+
+	for name, values := range header {
+		headerString, _ := StripEchoedURLs(strings.Join(values, "\n"), rawQuery)
+
+		for param, value := range params {
+			matched := matchEncodings(headerString, value, urlInfo.CanaryValue, encodings)
+
+			if len(matched) == 0 {
+				continue
+			}
+
+			reflection := foundParameters[param]
+			reflection.Headers = append(reflection.Headers, name)
+
+			for _, encoding := range matched {
+				if !slices.Contains(reflection.Encodings, encoding) {
+					reflection.Encodings = append(reflection.Encodings, encoding)
+				}
+			}
+
+			foundParameters[param] = reflection
+		}
+	}
+
+	for param := range foundParameters {
+		slices.Sort(foundParameters[param].Headers)
+	}
+
+	return foundParameters
+}
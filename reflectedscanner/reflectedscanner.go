@@ -3,29 +3,122 @@ package reflectedscanner
 import (
 	"strings"
 
+	"github.com/michael1026/paramfinderSlimmed/detector"
 	"github.com/michael1026/paramfinderSlimmed/types/scan"
-	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
-func CheckDocForReflections(body string, urlInfo *scan.URLInfo) []string {
-	foundParameters := make(map[string]struct{})
-	canaryCount := CountReflections(body, urlInfo.CanaryValue)
+// more reflected parameters than this in one chunk trips a re-split of the chunk
+const MaxReflectedParameters = 50
 
-	for param, value := range urlInfo.PotentialParameters {
-		counted := CountReflections(body, value)
+type Detector struct {
+	Encodings []string
+}
+
+func (Detector) Name() string {
+	return "reflection"
+}
+
+func (Detector) IgnoresBaseline() bool {
+	return true
+}
 
-		if counted > canaryCount {
-			foundParameters[param] = struct{}{}
-			if len(foundParameters) > 50 {
-				// Going to assume these are false positives. 50+ parameters should not exist on one URL
-				return []string{}
+func (Detector) EncodingProbeValue() string {
+	return EncodingProbeValue()
+}
+
+// the unmasked body is searched, a dynamic region can cover a reflection
+func (d Detector) Detect(urlInfo *scan.URLInfo, resp *detector.Response) []scan.Finding {
+	var findings []scan.Finding
+	encodings := encodingsOrRaw(d.Encodings)
+	body, _ := StripEchoedURLs(resp.RawBody, resp.RawQuery)
+
+	foundParameters := CheckDocForReflections(body, resp.Parameters, urlInfo, encodings)
+
+	if len(foundParameters) > MaxReflectedParameters {
+		return []scan.Finding{{Detector: "reflection", Reason: "too many reflected parameters"}}
+	}
+
+	for param, matched := range foundParameters {
+		var contexts []string
+		forms := EncodedForms(resp.Parameters[param], matched)
+
+		for _, encoding := range matched {
+			for _, context := range ClassifyContexts(body, resp.Header, forms[encoding]) {
+				if !slices.Contains(contexts, context) {
+					contexts = append(contexts, context)
+				}
 			}
 		}
+
+		findings = append(findings, scan.Finding{
+			Name:      param,
+			Detector:  "reflection",
+			Contexts:  contexts,
+			Encodings: matched,
+		})
+	}
+
+	return findings
+}
+
+/***********************************************************************
+*
+* Looks for every encoded form of each parameter value in the body.
+* Returns the encodings that matched for each reflected parameter.
+*
+************************************************************************/
+
+func CheckDocForReflections(body string, params map[string]string, urlInfo *scan.URLInfo, encodings []string) map[string][]string {
+	foundParameters := make(map[string][]string)
+
+	for param, value := range params {
+		matched := matchEncodings(body, value, urlInfo.CanaryValue, encodings)
+
+		if len(matched) > 0 {
+			foundParameters[param] = matched
+		}
 	}
 
-	return maps.Keys(foundParameters)
+	return foundParameters
 }
 
 func CountReflections(body string, canary string) int {
 	return strings.Count(body, canary)
 }
+
+// returns the encodings whose form of value is reflected more often than the same form of the canary
+func matchEncodings(body string, value string, canary string, encodings []string) []string {
+	var matched []string
+
+	forms := EncodedForms(value, encodings)
+	canaryForms := EncodedForms(canary, encodings)
+
+	for _, encoding := range encodings {
+		form, ok := forms[encoding]
+
+		if !ok {
+			continue
+		}
+
+		canaryCount := 0
+
+		if canaryForm, ok := canaryForms[encoding]; ok {
+			canaryCount = CountReflections(body, canaryForm)
+		}
+
+		if CountReflections(body, form) > canaryCount {
+			matched = append(matched, encoding)
+		}
+	}
+
+	return matched
+}
+
+func encodingsOrRaw(encodings []string) []string {
+	if len(encodings) == 0 {
+		return []string{"raw"}
+	}
+
+	return encodings
+}
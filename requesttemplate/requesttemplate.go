@@ -0,0 +1,209 @@
+package requesttemplate
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Marker is replaced with the candidate parameters when a request is built
+const Marker = "§PARAMS§"
+
+const (
+	LocationQuery       = "query"
+	LocationHeaderLines = "header-lines"
+	LocationHeaderValue = "header-value"
+	LocationCookie      = "cookie"
+	LocationJSON        = "json"
+	LocationXML         = "xml"
+	LocationForm        = "form"
+)
+
+type Param struct {
+	Name  string
+	Value string
+}
+
+type Template struct {
+	Method   string
+	Target   string
+	Headers  [][2]string
+	Body     string
+	Location string
+}
+
+/***********************************************************************
+*
+* Parses a raw HTTP request (request line, headers, blank line, body)
+* containing exactly one marker. Where the marker sits decides how the
+* parameters are written: query pairs in the request line, header lines
+* when the marker is a line of its own, cookie pairs in a Cookie header
+* and JSON, XML or form fields in the body depending on Content-Type.
+*
+************************************************************************/
+
+func Parse(raw string) (*Template, error) {
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+
+	if strings.Count(raw, Marker) != 1 {
+		return nil, fmt.Errorf("template needs exactly one %s marker", Marker)
+	}
+
+	head, body, _ := strings.Cut(raw, "\n\n")
+	lines := strings.Split(head, "\n")
+	requestLine := strings.Fields(lines[0])
+
+	if len(requestLine) < 2 {
+		return nil, errors.New("template has no request line")
+	}
+
+	t := &Template{
+		Method: requestLine[0],
+		Target: requestLine[1],
+		Body:   body,
+	}
+
+	contentType := ""
+
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if strings.TrimSpace(line) == Marker {
+			t.Location = LocationHeaderLines
+			t.Headers = append(t.Headers, [2]string{Marker, ""})
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+
+		if !ok {
+			return nil, fmt.Errorf("invalid header line %q", line)
+		}
+
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+
+		switch http.CanonicalHeaderKey(name) {
+		case "Host", "Content-Length":
+			// taken from the scanned URL and the built body
+			continue
+		case "Content-Type":
+			contentType = strings.ToLower(value)
+		}
+
+		if strings.Contains(value, Marker) {
+			t.Location = LocationHeaderValue
+
+			if http.CanonicalHeaderKey(name) == "Cookie" {
+				t.Location = LocationCookie
+			}
+		}
+
+		t.Headers = append(t.Headers, [2]string{name, value})
+	}
+
+	switch {
+	case strings.Contains(t.Target, Marker):
+		t.Location = LocationQuery
+	case strings.Contains(t.Body, Marker) && strings.Contains(contentType, "json"):
+		t.Location = LocationJSON
+	case strings.Contains(t.Body, Marker) && strings.Contains(contentType, "xml"):
+		t.Location = LocationXML
+	case strings.Contains(t.Body, Marker):
+		t.Location = LocationForm
+	}
+
+	if t.Location == "" {
+		// e.g. a header name, the method or a skipped Host header
+		return nil, fmt.Errorf("%s has to be in the target, a header value, the body or a line of its own", Marker)
+	}
+
+	return t, nil
+}
+
+/***********************************************************************
+*
+* Builds the request for one chunk. Scheme and host come from the
+* scanned URL, everything else from the template.
+*
+************************************************************************/
+
+func (t *Template) NewRequest(rawUrl string, params []Param) (*http.Request, error) {
+	base, err := url.Parse(rawUrl)
+
+	if err != nil {
+		return nil, err
+	}
+
+	target := t.Target
+	body := t.Body
+
+	if t.Location == LocationQuery {
+		target = strings.Replace(target, Marker, t.render(params), 1)
+	} else {
+		body = strings.Replace(body, Marker, t.render(params), 1)
+	}
+
+	ref, err := url.Parse(target)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(t.Method, base.ResolveReference(ref).String(), strings.NewReader(body))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range t.Headers {
+		if header[0] == Marker {
+			for _, param := range params {
+				req.Header.Add(param.Name, param.Value)
+			}
+			continue
+		}
+
+		req.Header.Add(header[0], strings.Replace(header[1], Marker, t.render(params), 1))
+	}
+
+	return req, nil
+}
+
+func (t *Template) render(params []Param) string {
+	var parts []string
+
+	for _, param := range params {
+		switch t.Location {
+		case LocationCookie:
+			parts = append(parts, param.Name+"="+param.Value)
+		case LocationJSON:
+			name, _ := json.Marshal(param.Name)
+			value, _ := json.Marshal(param.Value)
+			parts = append(parts, string(name)+":"+string(value))
+		case LocationXML:
+			value := &strings.Builder{}
+			xml.EscapeText(value, []byte(param.Value))
+			parts = append(parts, fmt.Sprintf("<%s>%s</%s>", param.Name, value.String(), param.Name))
+		default:
+			parts = append(parts, url.QueryEscape(param.Name)+"="+url.QueryEscape(param.Value))
+		}
+	}
+
+	switch t.Location {
+	case LocationCookie:
+		return strings.Join(parts, "; ")
+	case LocationJSON:
+		return strings.Join(parts, ",")
+	case LocationXML:
+		return strings.Join(parts, "")
+	}
+
+	return strings.Join(parts, "&")
+}
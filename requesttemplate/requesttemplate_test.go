@@ -0,0 +1,99 @@
+package requesttemplate
+
+import (
+	"io"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		// empty when Parse should fail
+		location string
+	}{
+		{"query", "GET /search?" + Marker + " HTTP/1.1\nHost: example.com\n\n", LocationQuery},
+		{"header lines", "GET / HTTP/1.1\nHost: example.com\n" + Marker + "\n\n", LocationHeaderLines},
+		{"header value", "GET / HTTP/1.1\nX-Options: " + Marker + "\n\n", LocationHeaderValue},
+		{"cookie", "GET / HTTP/1.1\nCookie: a=b; " + Marker + "\n\n", LocationCookie},
+		{"json", "POST /api HTTP/1.1\nContent-Type: application/json\n\n{" + Marker + "}", LocationJSON},
+		{"xml", "POST /api HTTP/1.1\nContent-Type: text/xml\n\n<a>" + Marker + "</a>", LocationXML},
+		{"form", "POST /login HTTP/1.1\nContent-Type: application/x-www-form-urlencoded\n\n" + Marker, LocationForm},
+		{"crlf", "POST /login HTTP/1.1\r\nContent-Type: application/x-www-form-urlencoded\r\n\r\n" + Marker, LocationForm},
+		{"no marker", "GET / HTTP/1.1\n\n", ""},
+		{"two markers", "GET /?" + Marker + " HTTP/1.1\nX-A: " + Marker + "\n\n", ""},
+		{"header name", "GET / HTTP/1.1\nX-" + Marker + ": 1\n\n", ""},
+		{"host header", "GET / HTTP/1.1\nHost: " + Marker + "\n\n", ""},
+		{"method", Marker + " / HTTP/1.1\n\n", ""},
+		{"no request line", Marker, ""},
+		{"invalid header", "GET /?" + Marker + " HTTP/1.1\nnot a header\n\n", ""},
+	}
+
+	for _, test := range tests {
+		template, err := Parse(test.raw)
+
+		if test.location == "" {
+			if err == nil {
+				t.Errorf("%s: expected an error, got location %q", test.name, template.Location)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		if template.Location != test.location {
+			t.Errorf("%s: location %q, want %q", test.name, template.Location, test.location)
+		}
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	params := []Param{{Name: "a", Value: "1"}, {Name: "b", Value: "x y"}}
+
+	tests := []struct {
+		name   string
+		raw    string
+		url    string
+		body   string
+		header [2]string
+	}{
+		{"query", "GET /search?" + Marker + " HTTP/1.1\n\n", "https://example.com/search?a=1&b=x+y", "", [2]string{}},
+		{"form", "POST /login HTTP/1.1\n\n" + Marker, "https://example.com/login", "a=1&b=x+y", [2]string{}},
+		{"json", "POST /api HTTP/1.1\nContent-Type: application/json\n\n{" + Marker + "}", "https://example.com/api", `{"a":"1","b":"x y"}`, [2]string{}},
+		{"cookie", "GET / HTTP/1.1\nCookie: " + Marker + "\n\n", "https://example.com/", "", [2]string{"Cookie", "a=1; b=x y"}},
+		{"header lines", "GET / HTTP/1.1\n" + Marker + "\n\n", "https://example.com/", "", [2]string{"B", "x y"}},
+	}
+
+	for _, test := range tests {
+		template, err := Parse(test.raw)
+
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		req, err := template.NewRequest("https://example.com/ignored", params)
+
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		if req.URL.String() != test.url {
+			t.Errorf("%s: url %q, want %q", test.name, req.URL.String(), test.url)
+		}
+
+		body, _ := io.ReadAll(req.Body)
+
+		if string(body) != test.body {
+			t.Errorf("%s: body %q, want %q", test.name, body, test.body)
+		}
+
+		if test.header[0] != "" && req.Header.Get(test.header[0]) != test.header[1] {
+			t.Errorf("%s: %s %q, want %q", test.name, test.header[0], req.Header.Get(test.header[0]), test.header[1])
+		}
+	}
+}
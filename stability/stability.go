@@ -0,0 +1,218 @@
+package stability
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+const maskPlaceholder = "§DYNAMIC§"
+
+// how much of the surrounding text is kept to anchor a dynamic region
+const anchorLength = 20
+
+// what a region may match when every sample only changed a single token
+const tokenPattern = `[A-Za-z0-9\-_:.+/=]*`
+
+type Sample struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+	Duration   time.Duration
+}
+
+/***********************************************************************
+*
+* Compares several responses to identical requests and builds a regex
+* for every region that changed between them (CSRF tokens, timestamps,
+* nonces). Each region is anchored on the text around it so it can be
+* found again in later responses. The URL is stable when every sample
+* has the same status code and the masked bodies are identical. Regions
+* never cross a line, and one that only ever held a token (timestamp,
+* CSRF token) only matches token characters, so text reflected between
+* the same anchors elsewhere on the page isn't masked with it.
+*
+************************************************************************/
+
+func Analyze(samples []Sample) ([]*regexp.Regexp, bool) {
+	if len(samples) == 0 {
+		return nil, false
+	}
+
+	regions := make(map[string]*regexp.Regexp)
+	firstLines := strings.Split(samples[0].Body, "\n")
+
+	for _, sample := range samples[1:] {
+		if sample.StatusCode != samples[0].StatusCode {
+			return nil, false
+		}
+
+		lines := strings.Split(sample.Body, "\n")
+
+		if len(lines) != len(firstLines) {
+			return nil, false
+		}
+
+		for i, line := range lines {
+			if line == firstLines[i] {
+				continue
+			}
+
+			region := regionFromLines(firstLines[i], line)
+
+			if region == nil {
+				return nil, false
+			}
+
+			regions[region.String()] = region
+		}
+	}
+
+	dynamicRegions := maps.Values(regions)
+	masked := Mask(samples[0].Body, dynamicRegions)
+
+	for _, sample := range samples[1:] {
+		if Mask(sample.Body, dynamicRegions) != masked {
+			return nil, false
+		}
+	}
+
+	return dynamicRegions, true
+}
+
+func Mask(body string, regions []*regexp.Regexp) string {
+	for _, region := range regions {
+		body = region.ReplaceAllString(body, "${1}"+maskPlaceholder+"${2}")
+	}
+
+	return body
+}
+
+/***********************************************************************
+*
+* Header names that only show up in some of the samples
+*
+************************************************************************/
+
+func DynamicHeaders(samples []Sample) []string {
+	var dynamic []string
+
+	for _, sample := range samples {
+		for name := range sample.Header {
+			if slices.Contains(dynamic, name) {
+				continue
+			}
+
+			for _, other := range samples {
+				if _, ok := other.Header[name]; !ok {
+					dynamic = append(dynamic, name)
+					break
+				}
+			}
+		}
+	}
+
+	return dynamic
+}
+
+func regionFromLines(a string, b string) *regexp.Regexp {
+	prefix := 0
+
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	// widen the region to whole tokens so a value sharing its first or last
+	// characters between samples doesn't end up in the anchor
+	for prefix > 0 && isTokenByte(a[prefix-1]) {
+		prefix--
+	}
+
+	for suffix > 0 && isTokenByte(a[len(a)-suffix]) {
+		suffix--
+	}
+
+	before := trimToRunes(a[:prefix])
+	after := trimToRunes(a[len(a)-suffix:])
+
+	if len(before) > anchorLength {
+		before = trimToRunes(before[len(before)-anchorLength:])
+	}
+
+	if len(after) > anchorLength {
+		after = trimToRunes(after[:anchorLength])
+	}
+
+	if before == "" && after == "" {
+		// the whole line changes, nothing to anchor on
+		return nil
+	}
+
+	start := regexp.QuoteMeta(before)
+	end := regexp.QuoteMeta(after)
+	middle := ".*?"
+
+	if isToken(a[prefix:len(a)-suffix]) && isToken(b[prefix:len(b)-suffix]) {
+		middle = tokenPattern
+	}
+
+	if before == "" {
+		start = "^"
+	}
+
+	if after == "" {
+		end = "$"
+	}
+
+	region, err := regexp.Compile("(?m)(" + start + ")" + middle + "(" + end + ")")
+
+	if err != nil {
+		return nil
+	}
+
+	return region
+}
+
+func isToken(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isTokenByte(s[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isTokenByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("-_:.+/=", c) >= 0
+}
+
+// drops partial runes left at either end after cutting on a byte offset
+func trimToRunes(s string) string {
+	for len(s) > 0 && !utf8.RuneStart(s[0]) {
+		s = s[1:]
+	}
+
+	for len(s) > 0 {
+		r, size := utf8.DecodeLastRuneInString(s)
+
+		if r != utf8.RuneError || size > 1 {
+			break
+		}
+
+		s = s[:len(s)-1]
+	}
+
+	return s
+}
@@ -0,0 +1,108 @@
+package stability
+
+import (
+	"strings"
+	"testing"
+)
+
+func samples(bodies ...string) []Sample {
+	var samples []Sample
+
+	for _, body := range bodies {
+		samples = append(samples, Sample{StatusCode: 200, Body: body})
+	}
+
+	return samples
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []Sample
+		stable  bool
+		regions int
+		// body masked with the regions found, and what it should become
+		body   string
+		masked string
+	}{
+		{
+			name:    "identical",
+			samples: samples("<p>a</p>\n<p>b</p>", "<p>a</p>\n<p>b</p>"),
+			stable:  true,
+		},
+		{
+			name:    "timestamp",
+			samples: samples("<span>12:03:04</span>\n<p>x</p>", "<span>12:03:09</span>\n<p>x</p>"),
+			stable:  true,
+			regions: 1,
+			body:    "<span>13:00:00</span>",
+			masked:  "<span>" + maskPlaceholder + "</span>",
+		},
+		{
+			name:    "timestamp anchors don't cover a reflection",
+			samples: samples("<span>12:03:04</span>", "<span>12:03:09</span>"),
+			stable:  true,
+			regions: 1,
+			body:    "<span>Results for abcdefghij</span>",
+			masked:  "<span>Results for abcdefghij</span>",
+		},
+		{
+			name:    "csrf token",
+			samples: samples(`<input name="csrf" value="a1b2c3">`, `<input name="csrf" value="z9y8x7">`),
+			stable:  true,
+			regions: 1,
+			body:    `<input name="csrf" value="q5w6e7">`,
+			masked:  `<input name="csrf" value="` + maskPlaceholder + `">`,
+		},
+		{
+			name:    "sentence",
+			samples: samples("<p>Hello alice, welcome</p>", "<p>Hello bob smith, welcome</p>"),
+			stable:  true,
+			regions: 1,
+			body:    "<p>Hello carol jones, welcome</p>",
+			masked:  "<p>Hello " + maskPlaceholder + ", welcome</p>",
+		},
+		{
+			name:    "status code",
+			samples: []Sample{{StatusCode: 200, Body: "a"}, {StatusCode: 500, Body: "a"}},
+		},
+		{
+			name:    "line count",
+			samples: samples("a\nb", "a\nb\nc"),
+		},
+		{
+			name:    "whole line",
+			samples: samples("abc\nx", "xyz\nx"),
+		},
+		{
+			name:    "no samples",
+			samples: nil,
+		},
+		{
+			name:    "multibyte anchors",
+			samples: samples(strings.Repeat("é", 30)+"123"+strings.Repeat("ü", 30), strings.Repeat("é", 30)+"456"+strings.Repeat("ü", 30)),
+			stable:  true,
+			regions: 1,
+		},
+	}
+
+	for _, test := range tests {
+		regions, stable := Analyze(test.samples)
+
+		if stable != test.stable {
+			t.Errorf("%s: stable %t, want %t", test.name, stable, test.stable)
+			continue
+		}
+
+		if len(regions) != test.regions {
+			t.Errorf("%s: %d regions %v, want %d", test.name, len(regions), regions, test.regions)
+			continue
+		}
+
+		if test.body != "" {
+			if masked := Mask(test.body, regions); masked != test.masked {
+				t.Errorf("%s: masked %q, want %q", test.name, masked, test.masked)
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package timingscanner
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/michael1026/paramfinderSlimmed/detector"
+	"github.com/michael1026/paramfinderSlimmed/types/scan"
+)
+
+// fewer baseline samples than this don't give a usable distribution
+const MinSamples = 10
+
+// how many standard deviations above the mean a response has to be
+const Sigma = 3
+
+// lower bound on the gap, a quiet host has almost no deviation and every hiccup would count
+const MinDelta = 250 * time.Millisecond
+
+type Detector struct{}
+
+func (Detector) Name() string {
+	return "timing"
+}
+
+func (Detector) Detect(urlInfo *scan.URLInfo, resp *detector.Response) []scan.Finding {
+	if !IsOutlier(resp.Duration, urlInfo.Baseline) {
+		return nil
+	}
+
+	reason := fmt.Sprintf("response time %s, baseline %s ± %s", resp.Duration.Round(time.Millisecond), urlInfo.Baseline.LatencyMean.Round(time.Millisecond), urlInfo.Baseline.LatencyStdDev.Round(time.Millisecond))
+
+	return []scan.Finding{{Detector: "timing", Reason: reason}}
+}
+
+// mean and standard deviation of the baseline response times
+func Latency(durations []time.Duration) (time.Duration, time.Duration) {
+	if len(durations) == 0 {
+		return 0, 0
+	}
+
+	var sum float64
+
+	for _, duration := range durations {
+		sum += float64(duration)
+	}
+
+	mean := sum / float64(len(durations))
+
+	var variance float64
+
+	for _, duration := range durations {
+		variance += math.Pow(float64(duration)-mean, 2)
+	}
+
+	variance /= float64(len(durations))
+
+	return time.Duration(mean), time.Duration(math.Sqrt(variance))
+}
+
+func IsOutlier(duration time.Duration, baseline scan.Baseline) bool {
+	delta := time.Duration(Sigma) * baseline.LatencyStdDev
+
+	if delta < MinDelta {
+		delta = MinDelta
+	}
+
+	return duration > baseline.LatencyMean+delta
+}
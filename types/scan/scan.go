@@ -1,5 +1,10 @@
 package scan
 
+import (
+	"regexp"
+	"time"
+)
+
 type URLInfo struct {
 	Stable              bool
 	CanaryCount         int
@@ -8,7 +13,39 @@ type URLInfo struct {
 	PotentialParameters map[string]string
 	MaxParams           int
 	CanaryValue         string
+	// sent with CanaryValue in every request for the URL, so the samples are identical
+	CanaryName          string
 	NumberOfCheckedURLs int
+	Baseline            Baseline
+	DynamicRegions      []*regexp.Regexp
+}
+
+type Baseline struct {
+	StatusCode     int
+	BodyLength     int
+	WordCount      int
+	LineCount      int
+	HeaderNames    []string
+	DynamicHeaders []string
+	// redirect target, the client doesn't follow redirects
+	Location string
+	// cookie name to attributes, see cookiescanner.Cookies
+	Cookies        map[string]string
+	DynamicCookies []string
+	LatencyMean    time.Duration
+	LatencyStdDev  time.Duration
+	// path to type for JSON responses, see jsonscanner.Shape
+	JSONShape        map[string]string
+	DynamicJSONPaths []string
+	DOM              DOM
+}
+
+// page structure for HTML responses, see domscanner.NewDOM
+type DOM struct {
+	TagCounts map[string]int
+	Title     string
+	Headings  []string
+	Forms     []string
 }
 
 type ScanResults map[string]*URLInfo
@@ -32,8 +69,21 @@ type JsonResult struct {
 }
 
 type Param struct {
-	Method string   `json:"method"`
-	Names  []string `json:"names"`
+	Method   string    `json:"method"`
+	Names    []string  `json:"names"`
+	Findings []Finding `json:"findings"`
+}
+
+type Finding struct {
+	Name      string   `json:"name"`
+	Detector  string   `json:"detector"`
+	Location  string   `json:"location,omitempty"`
+	Reason    string   `json:"reason,omitempty"`
+	Contexts  []string `json:"contexts,omitempty"`
+	Headers   []string `json:"headers,omitempty"`
+	Encodings []string `json:"encodings,omitempty"`
+	Confirmed bool     `json:"confirmed"`
+	Sources   []string `json:"sources,omitempty"`
 }
 
 type JsonResults map[string]JsonResult
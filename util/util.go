@@ -28,20 +28,11 @@ func JSONMarshal(t interface{}) ([]byte, error) {
 }
 
 func ResponseToBodyString(resp *http.Response) (body string) {
-	bodyString := ""
-
-	for name, values := range resp.Header {
-		for _, value := range values {
-			bodyString += fmt.Sprintf("%s: %s", name, value)
-		}
-	}
-
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		fmt.Printf("Error getting string %s\n", err)
-		return bodyString
+		return ""
 	}
-	bodyString = string(bodyBytes)
 
-	return bodyString
+	return string(bodyBytes)
 }